@@ -0,0 +1,209 @@
+// Package checker is the runtime of the validate functions generated by pigo checker, e.g.
+//
+//	if err := p.Validate(); err != nil {
+//		var errs checker.ValidationErrors
+//		if errors.As(err, &errs) {
+//			for _, e := range errs {
+//				log.Printf("%s: %v %v", e.Field, e.Code, e.Args)
+//			}
+//		}
+//		if errors.Is(err, checker.IsEmpty) {
+//			...
+//		}
+//	}
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code is the code of a failed rule, the rules without a custom type fail with IsEmpty or Invalid
+type Code string
+
+const (
+	// IsEmpty is the code of noempty
+	IsEmpty Code = "IsEmpty"
+	// Invalid is the code of the other rules
+	Invalid Code = "Invalid"
+)
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// FieldError is a failed rule of a field
+type FieldError struct {
+	// Field is the path of the field, e.g. "name" or "items[3].price"
+	Field string
+	// Other is the path of the field compared with by a cross-field rule, e.g. "end_at" of "start_at"
+	Other string
+	// Code is the custom type of the rule, or "IsEmpty" or "Invalid"
+	Code interface{}
+	// Args are the messages of the rule
+	Args []interface{}
+	// Err is the error returned by the function called, nil if the function returns a bool
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Field, e.Code)
+	if e.Other != "" {
+		msg = fmt.Sprintf("%s (%s): %v", e.Field, e.Other, e.Code)
+	}
+	if len(e.Args) > 0 {
+		msg += " " + strings.TrimSuffix(fmt.Sprintln(e.Args...), "\n")
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the code is target, a Code matches the string codes of the same value
+func (e *FieldError) Is(target error) bool {
+	switch code := e.Code.(type) {
+	case Code:
+		return code == target
+	case string:
+		return Code(code) == target
+	case error:
+		return errors.Is(code, target)
+	}
+	return false
+}
+
+// ValidationErrors are the failed rules of a validate function, in the order of the fields
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether one of the errors is target
+func (errs ValidationErrors) Is(target error) bool {
+	for _, e := range errs {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first of the errors which matches target
+func (errs ValidationErrors) As(target interface{}) bool {
+	for _, e := range errs {
+		if errors.As(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the errors of the field
+func (errs ValidationErrors) Fields(field string) ValidationErrors {
+	var result ValidationErrors
+	for _, e := range errs {
+		if e.Field == field {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// ParamChecker collects the failed rules, it is created by the generated functions
+type ParamChecker struct {
+	errs ValidationErrors
+}
+
+func NewParamChecker() *ParamChecker {
+	return &ParamChecker{}
+}
+
+// Assert adds the error of the field if ok is false, and returns ok
+func (c *ParamChecker) Assert(ok bool, field string, code interface{}, args ...interface{}) bool {
+	if !ok {
+		c.errs = append(c.errs, &FieldError{Field: field, Code: code, Args: args})
+	}
+	return ok
+}
+
+// AssertField adds the error of the field compared with the other field if ok is false, and returns ok
+func (c *ParamChecker) AssertField(ok bool, field string, other string, code interface{}, args ...interface{}) bool {
+	if !ok {
+		c.errs = append(c.errs, &FieldError{Field: field, Other: other, Code: code, Args: args})
+	}
+	return ok
+}
+
+// AssertError adds the error of the field if err is not nil, and returns whether err is nil
+func (c *ParamChecker) AssertError(err error, field string, code interface{}, args ...interface{}) bool {
+	if err != nil {
+		c.errs = append(c.errs, &FieldError{Field: field, Code: code, Args: args, Err: err})
+	}
+	return err == nil
+}
+
+// Merge adds the errors returned by another validate function,
+// an error which is not ValidationErrors is added as Invalid without a field
+func (c *ParamChecker) Merge(err error) {
+	if err == nil {
+		return
+	}
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		c.errs = append(c.errs, errs...)
+		return
+	}
+	c.errs = append(c.errs, &FieldError{Code: Invalid, Err: err})
+}
+
+// MergeField adds the errors returned by the validate function of the field, their fields are under the path of it,
+// an error which is not ValidationErrors is added as Invalid of the field
+func (c *ParamChecker) MergeField(field string, err error) {
+	if err == nil {
+		return
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		c.errs = append(c.errs, &FieldError{Field: field, Code: Invalid, Err: err})
+		return
+	}
+	for _, e := range errs {
+		nested := *e
+		nested.Field = fieldPath(field, e.Field)
+		if e.Other != "" {
+			nested.Other = fieldPath(field, e.Other)
+		}
+		c.errs = append(c.errs, &nested)
+	}
+}
+
+// fieldPath returns the path of the nested field in the field
+func fieldPath(field string, nested string) string {
+	switch {
+	case nested == "":
+		return field
+	case strings.HasPrefix(nested, "["):
+		return field + nested
+	default:
+		return field + "." + nested
+	}
+}
+
+// GetError returns the ValidationErrors, nil if no rule fails
+func (c *ParamChecker) GetError() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
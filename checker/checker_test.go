@@ -0,0 +1,110 @@
+package checker
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errCustom = errors.New("custom")
+
+func TestParamChecker(t *testing.T) {
+	chk := NewParamChecker()
+	if err := chk.GetError(); err != nil {
+		t.Fatalf("expect nil, got %v", err)
+	}
+
+	chk.Assert(true, "id", "IsEmpty")
+	chk.Assert(false, "name", "IsEmpty", "name", "is", "empty")
+	failed := errors.New("failed")
+	chk.AssertError(failed, "age", "Invalid")
+	chk.Assert(false, "kind", errCustom)
+
+	nested := NewParamChecker()
+	nested.Assert(false, "items[0]", Invalid)
+	chk.Merge(nested.GetError())
+	chk.Merge(nil)
+	chk.Merge(errors.New("other"))
+
+	err := chk.GetError()
+	expect := "name: IsEmpty name is empty; age: Invalid: failed; kind: custom; items[0]: Invalid; : Invalid: other"
+	if err.Error() != expect {
+		t.Errorf("expect %q, got %q", expect, err.Error())
+	}
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 5 {
+		t.Fatalf("expect 5 ValidationErrors, got %v", err)
+	}
+	if fields := errs.Fields("age"); len(fields) != 1 || fields[0].Err != failed {
+		t.Errorf("expect the error of age, got %v", fields)
+	}
+
+	wrapped := fmt.Errorf("validate: %w", err)
+	for _, target := range []error{IsEmpty, Invalid, failed, errCustom} {
+		if !errors.Is(wrapped, target) {
+			t.Errorf("expect %v found", target)
+		}
+	}
+	var fe *FieldError
+	if !errors.As(wrapped, &fe) || fe.Field != "name" {
+		t.Errorf("expect the first FieldError, got %v", fe)
+	}
+
+	chk = NewParamChecker()
+	chk.Assert(false, "name", "IsEmpty")
+	if errors.Is(chk.GetError(), Invalid) {
+		t.Error("expect Invalid not found")
+	}
+}
+
+func TestMergeField(t *testing.T) {
+	item := NewParamChecker()
+	item.Assert(false, "price", Invalid)
+	item.Assert(false, "tags[1]", IsEmpty)
+	item.AssertField(false, "start_at", "end_at", Invalid)
+
+	chk := NewParamChecker()
+	chk.MergeField("items[3]", item.GetError())
+	chk.MergeField("items[4]", nil)
+	chk.MergeField("owner", errors.New("failed"))
+
+	list := NewParamChecker()
+	list.Merge(errors.New("other"))
+	list.Assert(false, "[2]", IsEmpty)
+	chk.MergeField("list", list.GetError())
+
+	expect := "items[3].price: Invalid; items[3].tags[1]: IsEmpty; items[3].start_at (items[3].end_at): Invalid; owner: Invalid: failed; list: Invalid: other; list[2]: IsEmpty"
+	if err := chk.GetError(); err == nil || err.Error() != expect {
+		t.Errorf("expect %q, got %v", expect, err)
+	}
+	if item.GetError().(ValidationErrors)[0].Field != "price" {
+		t.Error("expect the merged errors not changed")
+	}
+}
+
+func TestRules(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		valid func(string) bool
+		good  []string
+		bad   []string
+	}{
+		{"email", IsEmail, []string{"a@b.com", "a.b+c@x-y.org"}, []string{"", "a", "a@", "@b.com", "a b@c.com"}},
+		{"url", IsURL, []string{"https://example.com/a?b=1", "ftp://x.org"}, []string{"", "example.com", "/a/b", "http://"}},
+		{"uuid", IsUUID, []string{"123e4567-e89b-12d3-a456-426614174000"}, []string{"", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"}},
+		{"regex", func(s string) bool { return MatchString(`^\d{2,3}$`, s) }, []string{"12", "123"}, []string{"1", "1234", "ab"}},
+		{"datetime", func(s string) bool { return IsDatetime("2006-01-02 15:04", s) }, []string{"2020-02-29 23:59"}, []string{"2021-02-29 23:59", "2020-01-01"}},
+	} {
+		for _, s := range c.good {
+			if !c.valid(s) {
+				t.Errorf("%s: expect %q valid", c.name, s)
+			}
+		}
+		for _, s := range c.bad {
+			if c.valid(s) {
+				t.Errorf("%s: expect %q invalid", c.name, s)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package checker
+
+import (
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// the helpers of the rules which are not generated inline, e.g. regex, email, url, uuid and datetime
+
+var (
+	emailRegexp = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
+	uuidRegexp  = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+
+	// the compiled patterns of MatchString
+	regexps sync.Map
+)
+
+// MatchString reports whether s matches the pattern, the pattern is compiled once,
+// it panics if the pattern is invalid, the patterns of the rules are checked when they are generated
+func MatchString(pattern string, s string) bool {
+	re, ok := regexps.Load(pattern)
+	if !ok {
+		re, _ = regexps.LoadOrStore(pattern, regexp.MustCompile(pattern))
+	}
+	return re.(*regexp.Regexp).MatchString(s)
+}
+
+// IsEmail reports whether s is an email address, as the email input of HTML
+func IsEmail(s string) bool {
+	return emailRegexp.MatchString(s)
+}
+
+// IsURL reports whether s is an absolute url with a host, e.g. https://example.com/a
+func IsURL(s string) bool {
+	u, err := url.ParseRequestURI(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// IsUUID reports whether s is a uuid in the canonical form, e.g. 123e4567-e89b-12d3-a456-426614174000
+func IsUUID(s string) bool {
+	return uuidRegexp.MatchString(s)
+}
+
+// IsDatetime reports whether s is a time in the layout of time.Parse
+func IsDatetime(layout string, s string) bool {
+	_, err := time.Parse(layout, s)
+	return err == nil
+}
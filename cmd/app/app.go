@@ -0,0 +1,221 @@
+// Package app is the pigo command line, a custom binary adds its generators by plugin.Register, see package plugin
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/lawrsp/pigo/cmd/checker"
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/cmd/convert"
+	"github.com/lawrsp/pigo/cmd/evalid"
+	"github.com/lawrsp/pigo/cmd/genrpc"
+	"github.com/lawrsp/pigo/cmd/inspect"
+	"github.com/lawrsp/pigo/cmd/jsonfield"
+	"github.com/lawrsp/pigo/cmd/pfilter"
+	"github.com/lawrsp/pigo/cmd/run"
+	"github.com/lawrsp/pigo/cmd/scan"
+	"github.com/lawrsp/pigo/cmd/setdb"
+	"github.com/lawrsp/pigo/cmd/setter"
+	"github.com/lawrsp/pigo/cmd/template"
+	"github.com/lawrsp/pigo/cmd/watch"
+	"github.com/lawrsp/pigo/plugin"
+)
+
+// New returns the pigo app, the registered plugins are added as the commands
+// it panics if a plugin has the name of a command
+func New(version string) *cli.App {
+	app := cli.NewApp()
+	app.Name = "pigo"
+	app.Usage = "go auto generate framework"
+
+	app.Version = version
+	app.UsageText = "pigo [global options] command [command options] [arguments...]"
+	app.Flags = []cli.Flag{
+		cli.BoolFlag{
+			Name:  "check",
+			Usage: "do not write, exit non-zero with a diff if the output is out of date",
+		},
+		cli.BoolFlag{
+			Name:  "verify",
+			Usage: "type check the package with the output before writing, nothing is written if it does not compile",
+		},
+		cli.IntFlag{
+			Name:  "jobs,j",
+			Usage: "run `N` generators at once, the number of CPUs if 0, the generators of the dependent packages run in order",
+		},
+		cli.StringFlag{
+			Name:  "loader",
+			Usage: "find the packages by `LOADER`: build, or packages to load them as the go command with type checking",
+			Value: "build",
+		},
+		cli.BoolFlag{
+			Name:  "no-cache",
+			Usage: "find and parse the imported packages every time, instead of keeping them in the user cache directory",
+		},
+	}
+	app.Flags = append(app.Flags, cmdutil.BuildFlags...)
+	app.Before = cmdutil.CheckFlags
+
+	commands := []cli.Command{
+		{
+			Name:        "convert",
+			Aliases:     []string{"t"},
+			UsageText:   "pigo convert [command options]",
+			Usage:       convert.Usage,
+			Description: convert.Description,
+			Flags:       convert.Flags,
+			Action:      convert.Action,
+		},
+		{
+			Name:        "genrpc",
+			Aliases:     []string{"g"},
+			UsageText:   "pigo genrpc [command options]",
+			Usage:       genrpc.Usage,
+			Description: genrpc.Description,
+			Flags:       genrpc.Flags,
+			Action:      genrpc.Action,
+		},
+		{
+			Name:        "setter",
+			Aliases:     []string{"s"},
+			UsageText:   "pigo convert [command options]",
+			Usage:       setter.Usage,
+			Description: setter.Description,
+			Flags:       setter.Flags,
+			Action:      setter.Action,
+		},
+		{
+			Name:        "evalid",
+			Aliases:     []string{"e"},
+			UsageText:   "pigo evalid [command options]",
+			Usage:       evalid.Usage,
+			Description: evalid.Description,
+			Flags:       evalid.Flags,
+			Action:      evalid.Action,
+		},
+		{
+			Name:        "jsonfield",
+			Aliases:     []string{"j"},
+			UsageText:   "pigo jsonfield [command options]",
+			Usage:       jsonfield.Usage,
+			Description: jsonfield.Description,
+			Flags:       jsonfield.Flags,
+			Action:      jsonfield.Action,
+		},
+		{
+			Name:        "checker",
+			Aliases:     []string{"c"},
+			UsageText:   "pigo checker [command options]",
+			Usage:       checker.Usage,
+			Description: checker.Description,
+			Flags:       checker.Flags,
+			Action:      checker.Action,
+		},
+		{
+			Name:        "setdb",
+			Aliases:     []string{"d"},
+			UsageText:   "pigo setdb [command options]",
+			Usage:       setdb.Usage,
+			Description: setdb.Description,
+			Flags:       setdb.Flags,
+			Action:      setdb.Action,
+		},
+		{
+			Name:        "pfilter",
+			Aliases:     []string{"p"},
+			UsageText:   "pigo pfilter [command options]",
+			Usage:       pfilter.Usage,
+			Description: pfilter.Description,
+			Flags:       pfilter.Flags,
+			Action:      pfilter.Action,
+		},
+		{
+			Name:        "template",
+			UsageText:   "pigo template [command options]",
+			Usage:       template.Usage,
+			Description: template.Description,
+			Flags:       template.Flags,
+			Action:      template.Action,
+		},
+		{
+			Name:        "inspect",
+			UsageText:   "pigo inspect [command options]",
+			Usage:       inspect.Usage,
+			Description: inspect.Description,
+			Flags:       inspect.Flags,
+			Action:      inspect.Action,
+		},
+		{
+			Name:        "run",
+			Aliases:     []string{"r"},
+			UsageText:   "pigo run [command options]",
+			Usage:       run.Usage,
+			Description: run.Description,
+			Flags:       run.Flags,
+			Action:      run.Action,
+		},
+		{
+			Name:        "scan",
+			UsageText:   "pigo scan [packages]",
+			Usage:       scan.Usage,
+			Description: scan.Description,
+			Flags:       scan.Flags,
+			Action:      scan.Action,
+		},
+		{
+			Name:        "watch",
+			UsageText:   "pigo watch [command options] [packages]",
+			Usage:       watch.Usage,
+			Description: watch.Description,
+			Flags:       watch.Flags,
+			Action:      watch.Action,
+		},
+	}
+
+	for _, p := range plugin.Plugins() {
+		for _, command := range commands {
+			if command.HasName(p.Name()) {
+				panic(fmt.Sprintf("plugin %s: the command exists", p.Name()))
+			}
+		}
+		commands = append(commands, plugin.Command(p))
+	}
+
+	for i := range commands {
+		addBuildFlags(&commands[i])
+	}
+	app.Commands = commands
+	return app
+}
+
+// addBuildFlags adds the build flags to the command, unless it has the flag of the name
+func addBuildFlags(command *cli.Command) {
+	names := map[string]bool{}
+	for _, f := range command.Flags {
+		for _, name := range strings.Split(f.GetName(), ",") {
+			names[strings.TrimSpace(name)] = true
+		}
+	}
+	for _, f := range cmdutil.BuildFlags {
+		if !names[f.GetName()] {
+			command.Flags = append(command.Flags, f)
+		}
+	}
+	command.Before = cmdutil.CheckFlags
+}
+
+// Main runs the app with the command line arguments
+func Main(version string) {
+	log.SetFlags(log.Lshortfile)
+	log.SetPrefix("pigo: ")
+
+	err := New(version).Run(os.Args)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,8 +3,8 @@ package checker
 import (
 	"fmt"
 	"log"
-	"strings"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -28,6 +28,18 @@ var Flags = []cli.Flag{
 		Name:  "import,i",
 		Usage: "the requried imports",
 	},
+	cli.StringFlag{
+		Name:  "checker",
+		Usage: "the `PACKAGE` of checker.NewParamChecker, the default is " + DefaultChecker,
+	},
+	cli.BoolFlag{
+		Name:  "context",
+		Usage: "generate Validate(ctx context.Context) error, and pass ctx to the functions taking it",
+	},
+	cli.BoolFlag{
+		Name:  "failfast",
+		Usage: "return at the first failed rule, instead of all of them",
+	},
 	cli.StringFlag{
 		Name:  "output,o",
 		Usage: "the `FILE` to output",
@@ -41,38 +53,27 @@ func Action(c *cli.Context) error {
 	}
 
 	tag := c.String("tag")
-	if tag == "" {
-		tag = "checker"
-	}
 	name := c.String("name")
-	if name == "" {
-		name = "Validate"
-	}
 
 	output := c.String("output")
 	config := &Config{
-		Type:    t,
-		Output:  output,
-		TagName: tag,
-		Name:    name,
+		Type:     t,
+		Output:   output,
+		TagName:  tag,
+		Name:     name,
+		Checker:  c.String("checker"),
+		Context:  c.Bool("context"),
+		FailFast: c.Bool("failfast"),
 	}
 
-	imports := c.StringSlice("import")
-	configImports := map[string]string{}
-	for _, impt := range imports {
-		kv := strings.Split(impt, ":")
-		if len(kv) == 1 {
-			path := kv[0]
-			names := strings.Split(path, "/")
-			name := names[len(names)-1]
-			configImports[name] = path
-		} else {
-			configImports[kv[0]] = kv[1]
-		}
-	}
+	configImports := cmdutil.Imports(c.StringSlice("import"))
 	config.Imports = configImports
 
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 	log.Printf("start generate chekcer:")
 	return g.Generate(config)
 }
@@ -1,27 +1,40 @@
 package checker
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/token"
-	"log"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/lawrsp/pigo/generator"
 	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
-	"github.com/lawrsp/pigo/generator/printutil"
 	"github.com/lawrsp/stringstyles"
 )
 
+// DefaultChecker is the package of checker.NewParamChecker used by the generated functions, see Config.Checker
+const DefaultChecker = "github.com/lawrsp/pigo/checker"
+
 type Config struct {
+	Dir     string
 	Type    string
 	Name    string
 	Output  string
 	TagName string
 	Imports map[string]string
+	// Checker is the import path of the package providing NewParamChecker, DefaultChecker if empty,
+	// a custom one has the API of DefaultChecker, an import named checker in Imports is used instead too
+	Checker string
+	// Context generates Validate(ctx context.Context) error,
+	// the ctx is passed to the functions of call, isvalid and merge which take it first, and to the nested types
+	Context bool
+	// FailFast returns at the first failed rule, all the failed rules are returned by default
+	FailFast bool
 }
 
 type Generator struct {
@@ -39,6 +52,38 @@ type CheckerInfo struct {
 	fullExpr   string
 	targetType parser.Type
 	file       *parser.File
+	// method is the name of the function generated, the nested types are validated by it
+	method string
+	// siblings are the fields of the struct, referenced by the cross-field rules
+	siblings []*sibling
+	// ctx is the context of the function, empty if it has no context
+	ctx string
+	// failFast returns after each rule
+	failFast bool
+}
+
+// sibling is a field of the struct validated
+type sibling struct {
+	goName     string
+	name       string
+	expr       string
+	targetType parser.Type
+}
+
+// sibling returns the field of the struct by its go name or its name in the errors
+func (c *CheckerInfo) sibling(ref string) *sibling {
+	for _, s := range c.siblings {
+		if s.goName == ref {
+			return s
+		}
+	}
+	for _, s := range c.siblings {
+		if s.name == ref {
+			return s
+		}
+	}
+	errutil.Throwf("cannot find the field %s referenced by %s", ref, c.name)
+	return nil
 }
 
 func (c *CheckerInfo) Copy() *CheckerInfo {
@@ -58,10 +103,10 @@ type Checker struct {
 func NewChecker(c *CheckerInfo, p builder.Printer, tagSrc string) *Checker {
 	cc := &Checker{c: c, p: p, index: 0, procs: []CheckerProc{}}
 
-	confs := strings.Split(tagSrc, ";")
+	confs := splitRule(tagSrc, ';')
 
 	for _, cf := range confs {
-		vs := strings.Split(cf, ":")
+		vs := splitRule(cf, ':')
 		switch vs[0] {
 		case "noempty":
 			proc := NewNoEmptyProc(vs)
@@ -90,6 +135,27 @@ func NewChecker(c *CheckerInfo, p builder.Printer, tagSrc string) *Checker {
 		case "convert":
 			proc := NewConvertProc(vs)
 			cc.procs = append(cc.procs, proc)
+		case "dive":
+			proc := NewDiveProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "len", "range":
+			proc := NewBoundProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "oneof":
+			proc := NewOneOfProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "regex", "prefix", "suffix", "datetime":
+			proc := NewMatchProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "email", "url", "uuid", "ip":
+			proc := NewFormatProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+			proc := NewFieldCompareProc(vs)
+			cc.procs = append(cc.procs, proc)
+		case "required_if", "required_with", "excluded_unless":
+			proc := NewRequiredProc(vs)
+			cc.procs = append(cc.procs, proc)
 		}
 	}
 
@@ -101,7 +167,25 @@ func (c *Checker) Next() {
 		idx := c.index
 		c.index += 1
 		c.procs[idx].Print(c)
+		if c.c.failFast && assertsRule(c.procs[idx]) {
+			c.printFailFast()
+		}
+	}
+}
+
+// assertsRule reports whether the proc adds errors, the others step into the values or change them,
+// the dive prints its own return
+func assertsRule(proc CheckerProc) bool {
+	switch proc.(type) {
+	case *StarProc, *ArrayProc, *DefaultValueProc, *ConvertProc, *DiveProc:
+		return false
 	}
+	return true
+}
+
+// printFailFast returns the error if a rule failed
+func (c *Checker) printFailFast() {
+	c.p.Printf("if err := %s.GetError(); err != nil {\nreturn err\n}\n", c.c.chk)
 }
 
 type CheckerProc interface {
@@ -113,6 +197,31 @@ type baseCheckerProc struct {
 	Messages []string
 }
 
+// setError sets the CustomType and the messages from the index of the CustomType in vs, the default is Invalid
+func (proc *baseCheckerProc) setError(vs []string, index int) {
+	proc.ErrType = "\"Invalid\""
+	if len(vs) > index && vs[index] != "" {
+		proc.ErrType = vs[index]
+	}
+	if len(vs) > index+1 {
+		proc.Messages = vs[index+1:]
+	}
+}
+
+// printAssert prints the assert of the condition
+func (proc *baseCheckerProc) printAssert(cm *Checker, cond string) {
+	p := cm.p
+	c := cm.c
+
+	p.Printf("%s.Assert(%s, %s, %s", c.chk, cond, c.name, proc.ErrType)
+	for _, msg := range proc.Messages {
+		if msg != "" {
+			p.Printf(", \"%s\"", msg)
+		}
+	}
+	p.Printf(")\n")
+}
+
 type NoEmptyProc struct {
 	baseCheckerProc
 	emptyVal string
@@ -188,7 +297,11 @@ func (cp *IsValidProc) Print(cm *Checker) {
 	p := cm.p
 	c := cm.c
 
-	p.Printf("%s.Assert(%s(%s), %s, %s", c.chk, cp.call, c.expr, c.name, cp.ErrType)
+	args := c.expr
+	if c.ctx != "" && acceptsContext(findFunc(c.file, cp.call)) {
+		args = c.ctx + ", " + args
+	}
+	p.Printf("%s.Assert(%s(%s), %s, %s", c.chk, cp.call, args, c.name, cp.ErrType)
 
 	for _, msg := range cp.Messages {
 		if msg != "" {
@@ -233,15 +346,16 @@ func (cp *CallProc) Print(cm *Checker) {
 	for expr[0] == '*' {
 		expr = expr[1:]
 	}
+	args := contextArgs(c, cp.call)
 
 	if cp.result == "error" || cp.result == "" {
-		p.Printf("%s.AssertError(%s.%s(), %s, %s", c.chk, expr, cp.call, c.name, cp.ErrType)
+		p.Printf("%s.AssertError(%s.%s(%s), %s, %s", c.chk, expr, cp.call, args, c.name, cp.ErrType)
 	} else if cp.result == "bool" || cp.result == "true" {
-		p.Printf("%s.Assert(%s.%s(), %s, %s", c.chk, expr, cp.call, c.name, cp.ErrType)
+		p.Printf("%s.Assert(%s.%s(%s), %s, %s", c.chk, expr, cp.call, args, c.name, cp.ErrType)
 	} else if cp.result == "false" {
-		p.Printf("%s.Assert(!%s.%s(), %s, %s", c.chk, expr, cp.call, c.name, cp.ErrType)
+		p.Printf("%s.Assert(!%s.%s(%s), %s, %s", c.chk, expr, cp.call, args, c.name, cp.ErrType)
 	} else {
-		log.Fatalf("cannot generate call checker %s:", cp.call)
+		errutil.Throwf("cannot generate call checker %s: unknown result %s", cp.call, cp.result)
 	}
 
 	for _, msg := range cp.Messages {
@@ -335,14 +449,517 @@ func (cp *MergeProc) Print(cm *Checker) {
 	p := cm.p
 	c := cm.c
 
-	p.Printf("if err := %s.%s(); err != nil {\n", c.expr, cp.method)
+	p.Printf("if err := %s.%s(%s); err != nil {\n", c.expr, cp.method, contextArgs(c, cp.method))
 	p.Printf("%s.Merge(err)\n", c.chk)
 	p.Printf("}\n")
 
 }
 
+// contextArgs returns the ctx if the method of the value takes it first
+func contextArgs(c *CheckerInfo, method string) string {
+	if c.ctx == "" {
+		return ""
+	}
+	for _, fd := range parser.Methods(c.targetType) {
+		if fd.Name.Name == method && acceptsContext(fd) {
+			return c.ctx
+		}
+	}
+	return ""
+}
+
+// findFunc finds the function declared in the package of the file, or in the package imported, e.g. "valid.Name"
+func findFunc(file *parser.File, name string) *ast.FuncDecl {
+	if file == nil {
+		return nil
+	}
+	files := []*parser.File{file}
+	if file.BelongTo != nil {
+		files = file.BelongTo.Files
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		pkg := file.FindImport(name[:i])
+		if pkg == nil {
+			return nil
+		}
+		files = pkg.Files
+		name = name[i+1:]
+	}
+	for _, f := range files {
+		for _, decl := range f.File.Decls {
+			if fd, ok := decl.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == name {
+				return fd
+			}
+		}
+	}
+	return nil
+}
+
+// acceptsContext reports whether the first param of the function is a context.Context
+func acceptsContext(fd *ast.FuncDecl) bool {
+	if fd == nil || fd.Type.Params == nil || len(fd.Type.Params.List) == 0 {
+		return false
+	}
+	return parser.ExprToString(fd.Type.Params.List[0].Type) == "context.Context"
+}
+
+type BoundProc struct {
+	baseCheckerProc
+	rule string
+	min  string
+	max  string
+}
+
+// len:min:max:CustomType:MsgA:MsgB...
+// range:min:max:CustomType:MsgA:MsgB...
+// the bound omitted is not checked, len is the bytes of a string
+func NewBoundProc(vs []string) CheckerProc {
+	proc := &BoundProc{rule: vs[0]}
+	if len(vs) > 1 {
+		proc.min = vs[1]
+	}
+	if len(vs) > 2 {
+		proc.max = vs[2]
+	}
+	if proc.min == "" && proc.max == "" {
+		errutil.Throwf("%s should have min or max", proc.rule)
+	}
+	proc.setError(vs, 3)
+	return proc
+}
+
+func (cp *BoundProc) Print(cm *Checker) {
+	expr := cm.c.expr
+	if cp.rule == "len" {
+		expr = fmt.Sprintf("len(%s)", expr)
+	}
+
+	conds := []string{}
+	if cp.min != "" {
+		conds = append(conds, fmt.Sprintf("%s >= %s", expr, cp.min))
+	}
+	if cp.max != "" {
+		conds = append(conds, fmt.Sprintf("%s <= %s", expr, cp.max))
+	}
+	cp.printAssert(cm, strings.Join(conds, " && "))
+}
+
+type OneOfProc struct {
+	baseCheckerProc
+	values []string
+}
+
+// oneof:a|b|c:CustomType:MsgA:MsgB...
+// the values are quoted if the type is a string
+func NewOneOfProc(vs []string) CheckerProc {
+	proc := &OneOfProc{}
+	if len(vs) < 2 || vs[1] == "" {
+		errutil.Throwf("oneof should have values")
+	}
+	proc.values = strings.Split(vs[1], "|")
+	proc.setError(vs, 2)
+	return proc
+}
+
+func (cp *OneOfProc) Print(cm *Checker) {
+	c := cm.c
+	isString := isStringType(c.targetType)
+
+	conds := []string{}
+	for _, v := range cp.values {
+		if isString || isQuoted(v) {
+			v = quoteArg(v)
+		}
+		conds = append(conds, fmt.Sprintf("%s == %s", c.expr, v))
+	}
+	cp.printAssert(cm, strings.Join(conds, " || "))
+}
+
+type MatchProc struct {
+	baseCheckerProc
+	rule string
+	arg  string
+}
+
+// regex:'pattern':CustomType:MsgA:MsgB...
+// prefix:value:CustomType:MsgA:MsgB...
+// suffix:value:CustomType:MsgA:MsgB...
+// datetime:'layout':CustomType:MsgA:MsgB...
+// the argument is in single quotes if it has : or ;
+func NewMatchProc(vs []string) CheckerProc {
+	proc := &MatchProc{rule: vs[0]}
+	if len(vs) < 2 || vs[1] == "" {
+		errutil.Throwf("%s should have a value", proc.rule)
+	}
+	proc.arg = quoteArg(vs[1])
+	if proc.rule == "regex" {
+		pattern, _ := strconv.Unquote(proc.arg)
+		if _, err := regexp.Compile(pattern); err != nil {
+			errutil.Throwf("invalid regex %s: %w", proc.arg, err)
+		}
+	}
+	proc.setError(vs, 2)
+	return proc
+}
+
+func (cp *MatchProc) Print(cm *Checker) {
+	expr := stringExpr(cm.c)
+
+	var cond string
+	switch cp.rule {
+	case "regex":
+		cond = fmt.Sprintf("checker.MatchString(%s, %s)", cp.arg, expr)
+	case "prefix":
+		cond = fmt.Sprintf("strings.HasPrefix(%s, %s)", expr, cp.arg)
+	case "suffix":
+		cond = fmt.Sprintf("strings.HasSuffix(%s, %s)", expr, cp.arg)
+	case "datetime":
+		cond = fmt.Sprintf("checker.IsDatetime(%s, %s)", cp.arg, expr)
+	}
+	cp.printAssert(cm, cond)
+}
+
+type FormatProc struct {
+	baseCheckerProc
+	format string
+}
+
+// email:CustomType:MsgA:MsgB...
+// url:CustomType:MsgA:MsgB...
+// uuid:CustomType:MsgA:MsgB...
+// ip:CustomType:MsgA:MsgB...
+func NewFormatProc(vs []string) CheckerProc {
+	proc := &FormatProc{format: vs[0]}
+	proc.setError(vs, 1)
+	return proc
+}
+
+func (cp *FormatProc) Print(cm *Checker) {
+	expr := stringExpr(cm.c)
+
+	var cond string
+	switch cp.format {
+	case "email":
+		cond = fmt.Sprintf("checker.IsEmail(%s)", expr)
+	case "url":
+		cond = fmt.Sprintf("checker.IsURL(%s)", expr)
+	case "uuid":
+		cond = fmt.Sprintf("checker.IsUUID(%s)", expr)
+	case "ip":
+		cond = fmt.Sprintf("net.ParseIP(%s) != nil", expr)
+	}
+	cp.printAssert(cm, cond)
+}
+
+func isStringType(t parser.Type) bool {
+	bt, ok := t.Underlying().(*parser.BasicType)
+	return ok && bt.Name() == "string"
+}
+
+// stringExpr returns the expr converted to string, unless it is a string
+func stringExpr(c *CheckerInfo) string {
+	if bt, ok := c.targetType.(*parser.BasicType); ok && bt.Name() == "string" {
+		return c.expr
+	}
+	return fmt.Sprintf("string(%s)", c.expr)
+}
+
+func isQuoted(v string) bool {
+	return len(v) > 1 && v[0] == '\'' && v[len(v)-1] == '\''
+}
+
+// quoteArg returns the string literal of the argument, the single quotes around it are removed
+func quoteArg(v string) string {
+	if isQuoted(v) {
+		v = v[1 : len(v)-1]
+	}
+	return strconv.Quote(v)
+}
+
+// splitRule splits the rules by sep, the seps in an argument quoted by single quotes are kept,
+// e.g. "regex:'^a:b$':CustomType", a quote in the middle of an argument is not a quote, e.g. "can't"
+func splitRule(s string, sep byte) []string {
+	parts := []string{}
+	start := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'' && !quoted && (i == start || s[i-1] == ':'):
+			quoted = true
+		case s[i] == '\'' && quoted && (i+1 == len(s) || s[i+1] == ':' || s[i+1] == ';'):
+			quoted = false
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// the operators of the cross-field rules, and the methods of the types like time.Time
+var fieldOperators = map[string]struct {
+	operand string
+	method  string
+	negate  bool
+}{
+	"eqfield":  {"==", "Equal", false},
+	"nefield":  {"!=", "Equal", true},
+	"gtfield":  {">", "After", false},
+	"gtefield": {">=", "Before", true},
+	"ltfield":  {"<", "Before", false},
+	"ltefield": {"<=", "After", true},
+}
+
+type FieldCompareProc struct {
+	baseCheckerProc
+	rule  string
+	other string
+}
+
+// eqfield:OtherField:CustomType:MsgA:MsgB...
+// nefield, gtfield, gtefield, ltfield, ltefield are the same,
+// the other field is the go name or the name in the errors, e.g. ltfield:EndAt or ltfield:end_at
+func NewFieldCompareProc(vs []string) CheckerProc {
+	proc := &FieldCompareProc{rule: vs[0]}
+	if len(vs) < 2 || vs[1] == "" {
+		errutil.Throwf("%s should have a field", proc.rule)
+	}
+	proc.other = vs[1]
+	proc.setError(vs, 2)
+	return proc
+}
+
+// Print compares by the operator, or by the method if the type has it, e.g. StartAt.Before(EndAt) of time.Time
+func (cp *FieldCompareProc) Print(cm *Checker) {
+	c := cm.c
+	other := c.sibling(cp.other)
+	op := fieldOperators[cp.rule]
+
+	var cond string
+	if hasMethod(c.targetType, op.method) {
+		cond = fmt.Sprintf("%s.%s(%s)", c.expr, op.method, other.expr)
+		if op.negate {
+			cond = "!" + cond
+		}
+	} else {
+		cond = fmt.Sprintf("%s %s %s", c.expr, op.operand, other.expr)
+	}
+	cp.printFieldAssert(cm, cond, other)
+}
+
+type RequiredProc struct {
+	baseCheckerProc
+	rule  string
+	other string
+	value string
+}
+
+// required_if:OtherField:value:CustomType:MsgA:MsgB...
+// required_with:OtherField:CustomType:MsgA:MsgB...
+// excluded_unless:OtherField:value:CustomType:MsgA:MsgB...
+// the field is not empty if the other field is the value, or is not empty,
+// or the field is empty unless the other field is the value,
+// the value is quoted if the other field is a string
+func NewRequiredProc(vs []string) CheckerProc {
+	proc := &RequiredProc{rule: vs[0]}
+	if len(vs) < 2 || vs[1] == "" {
+		errutil.Throwf("%s should have a field", proc.rule)
+	}
+	proc.other = vs[1]
+	index := 2
+	if proc.rule != "required_with" {
+		if len(vs) < 3 {
+			errutil.Throwf("%s should have a value", proc.rule)
+		}
+		proc.value = vs[2]
+		index = 3
+	}
+	proc.setError(vs, index)
+	if len(vs) <= index || vs[index] == "" {
+		if proc.rule != "excluded_unless" {
+			proc.ErrType = "\"IsEmpty\""
+		}
+	}
+	return proc
+}
+
+func (cp *RequiredProc) Print(cm *Checker) {
+	c := cm.c
+	other := c.sibling(cp.other)
+
+	value := cp.value
+	if isStringType(other.targetType) || isQuoted(value) {
+		value = quoteArg(value)
+	}
+
+	var cond string
+	switch cp.rule {
+	case "required_if":
+		cond = fmt.Sprintf("%s != %s || !%s", other.expr, value, emptyCond(c.expr, c.targetType, c.file))
+	case "required_with":
+		cond = fmt.Sprintf("%s || !%s", emptyCond(other.expr, other.targetType, c.file), emptyCond(c.expr, c.targetType, c.file))
+	case "excluded_unless":
+		cond = fmt.Sprintf("%s == %s || %s", other.expr, value, emptyCond(c.expr, c.targetType, c.file))
+	}
+	cp.printFieldAssert(cm, cond, other)
+}
+
+// printFieldAssert prints the assert of the condition, the error names the other field too
+func (proc *baseCheckerProc) printFieldAssert(cm *Checker, cond string, other *sibling) {
+	p := cm.p
+	c := cm.c
+
+	p.Printf("%s.AssertField(%s, %s, \"%s\", %s", c.chk, cond, c.name, other.name, proc.ErrType)
+	for _, msg := range proc.Messages {
+		if msg != "" {
+			p.Printf(", \"%s\"", msg)
+		}
+	}
+	p.Printf(")\n")
+}
+
+// emptyCond returns the condition of the empty value, in parentheses if it is a comparison
+func emptyCond(expr string, t parser.Type, file *parser.File) string {
+	if _, ok := t.Underlying().(*parser.ArrayType); ok {
+		return fmt.Sprintf("(len(%s) == 0)", expr)
+	}
+	if hasMethod(t, "IsZero") {
+		return fmt.Sprintf("%s.IsZero()", expr)
+	}
+	emptyValue := getEmptyValueString(parser.TypeZeroValue(t, file))
+	return fmt.Sprintf("(%s == %s)", expr, emptyValue)
+}
+
+// hasMethod reports whether the type has the method, the pointers are compared to nil, not by the methods
+func hasMethod(t parser.Type, name string) bool {
+	if _, ok := t.Underlying().(*parser.PointerType); ok {
+		return false
+	}
+	for _, fd := range parser.Methods(t) {
+		if fd.Name.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type DiveProc struct {
+	method string
+}
+
+// dive
+// dive:other-valid-function
+func NewDiveProc(vs []string) CheckerProc {
+	proc := &DiveProc{}
+	if len(vs) > 1 {
+		proc.method = vs[1]
+	}
+	return proc
+}
+
+// Print validates the value by its own function, the errors are merged under the path of the value,
+// the pointers and the elements of the slices and arrays are stepped into first
+func (cp *DiveProc) Print(cm *Checker) {
+	c := cm.c
+	method := cp.method
+	if method == "" {
+		method = c.method
+	}
+
+	// the copies of the underlying types are changed by the procs, not the named types
+	var steps []CheckerProc
+	switch x := c.targetType.Underlying().(type) {
+	case *parser.PointerType:
+		// the nil pointers are skipped
+		steps = []CheckerProc{&StarProc{stars: x.Stars}}
+	case *parser.ArrayType:
+		arrays := x.Slices
+		if arrays == 0 {
+			arrays = 1
+		}
+		steps = []CheckerProc{&ArrayProc{arrays: arrays, byIndex: true}}
+	case *parser.StructType:
+	default:
+		errutil.Throwf("cannot dive into %s of %s", c.targetType, c.expr)
+	}
+	if steps != nil {
+		nc := c.Copy()
+		nc.targetType = c.targetType.Underlying().Copy()
+		cm.c = nc
+		steps = append(steps, &DiveProc{method: method})
+		procs := append([]CheckerProc{}, cm.procs[:cm.index]...)
+		procs = append(procs, steps...)
+		cm.procs = append(procs, cm.procs[cm.index:]...)
+		cm.Next()
+		return
+	}
+
+	// the nested types are generated with the same signature
+	p := cm.p
+	p.Printf("if err := %s.%s(%s); err != nil {\n", receiverExpr(c), method, c.ctx)
+	p.Printf("%s.MergeField(%s, err)\n", c.chk, c.name)
+	if c.failFast {
+		p.Printf("return %s.GetError()\n", c.chk)
+	}
+	p.Printf("}\n")
+}
+
+// receiverExpr returns the addressable value, so the changes of the function are kept,
+// or the pointer to it if it is dereferenced
+func receiverExpr(c *CheckerInfo) string {
+	expr := c.expr
+	if c.fullExpr != "" {
+		expr = c.fullExpr
+	}
+	if !strings.HasPrefix(expr, "*") {
+		return expr
+	}
+	expr = expr[1:]
+	if strings.HasPrefix(expr, "*") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
+
+// diveType returns the struct type validated by dive, nil if the type has no rules of the tag,
+// the pointers and the elements of the slices and arrays are stepped into
+func diveType(t parser.Type, tagName string) parser.Type {
+	for {
+		switch x := t.Underlying().(type) {
+		case *parser.PointerType:
+			t = x.Base
+			continue
+		case *parser.ArrayType:
+			t = x.Element
+			continue
+		case *parser.StructType:
+			// the anonymous structs have no functions
+			if t.Name() == "" {
+				return nil
+			}
+		default:
+			return nil
+		}
+		break
+	}
+
+	tagged := false
+	parser.InspectUnderlyingStruct(t, func(field *parser.Field) bool {
+		if reflect.StructTag(field.Tag).Get(tagName) != "" {
+			tagged = true
+		}
+		return false
+	})
+	if !tagged {
+		return nil
+	}
+	return t
+}
+
 type ArrayProc struct {
 	arrays int
+	// byIndex declares no variable of the elements, the next procs use them by the indexes
+	byIndex bool
 }
 
 // arrays:123
@@ -352,6 +969,7 @@ func NewArrayProc(vs []string) CheckerProc {
 	return proc
 }
 
+// Print ranges over the indexes, so each index is used, by the element and the path of the error
 func (cp *ArrayProc) Print(cm *Checker) {
 	if cp.arrays == 0 {
 		return
@@ -361,15 +979,25 @@ func (cp *ArrayProc) Print(cm *Checker) {
 	c := cm.c
 
 	expr := c.expr
-	fullExpr := expr
+	fullExpr := c.expr
+	if c.fullExpr != "" {
+		fullExpr = c.fullExpr
+	}
 	it := "i"
 	idx := ""
+	idxes := []string{}
 
 	for i := 0; i < cp.arrays; i++ {
 		it += "t"
 		idx += "i"
-		fullExpr = fmt.Sprintf("%s[%s]", expr, idx)
-		p.Printf("for %s, %s := range %s {\n", idx, it, expr)
+		idxes = append(idxes, idx)
+		fullExpr = indexExpr(fullExpr, idx)
+		p.Printf("for %s := range %s {\n", idx, expr)
+		if cp.byIndex && i == cp.arrays-1 {
+			it = fullExpr
+			break
+		}
+		p.Printf("%s := %s\n", it, indexExpr(expr, idx))
 		expr = it
 	}
 
@@ -380,10 +1008,10 @@ func (cp *ArrayProc) Print(cm *Checker) {
 		var err error
 		theName, err = strconv.Unquote(theName)
 		if err != nil {
-			log.Fatalf("unquote %s failed: %v", c.name, err)
+			errutil.Throwf("unquote %s failed: %w", c.name, err)
 		}
 	}
-	nc.name = "fmt.Sprintf(\"" + theName + ".%d\", " + idx + ")"
+	nc.name = "fmt.Sprintf(\"" + theName + strings.Repeat("[%d]", cp.arrays) + "\", " + strings.Join(idxes, ", ") + ")"
 	nc.targetType = parser.TypeSkipBracket(c.targetType, cp.arrays)
 	nc.fullExpr = fullExpr
 	cm.c = nc
@@ -394,6 +1022,14 @@ func (cp *ArrayProc) Print(cm *Checker) {
 	}
 }
 
+// indexExpr returns expr[idx], expr is in parentheses if it is dereferenced
+func indexExpr(expr string, idx string) string {
+	if strings.HasPrefix(expr, "*") {
+		expr = "(" + expr + ")"
+	}
+	return fmt.Sprintf("%s[%s]", expr, idx)
+}
+
 type ConvertProc struct {
 	convertTo   string
 	convertName string
@@ -423,6 +1059,9 @@ func (cp *ConvertProc) Print(cm *Checker) {
 		convertedExpr = "cnvt" + exprDotSlice[len(exprDotSlice)-1]
 	}
 	convertType := cm.c.file.ReduceTypeSrc(cp.convertTo)
+	if convertType == nil {
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(cp.convertTo))
+	}
 
 	p.Printf("%s := %s(%s)\n", convertedExpr, cp.convertTo, expr)
 
@@ -463,6 +1102,9 @@ func (cp *StarProc) Print(cm *Checker) {
 
 	nc := c.Copy()
 	nc.expr = strings.Repeat("*", cp.stars) + c.expr
+	if c.fullExpr != "" {
+		nc.fullExpr = strings.Repeat("*", cp.stars) + c.fullExpr
+	}
 	nc.targetType = parser.TypeSkipPointer(c.targetType, cp.stars)
 	cm.c = nc
 	cm.Next()
@@ -506,8 +1148,7 @@ func getEmptyValueString(expr ast.Expr) string {
 		}
 	}
 
-	printutil.PrintNodef(expr, "cannot support expr:")
-	log.Fatalf("cannot get value string")
+	errutil.Throw(errutil.New("cannot get value string").WithExpr(parser.ExprToString(expr)))
 	return ""
 }
 
@@ -515,7 +1156,7 @@ func (g *Generator) Run(c *Config) {
 
 	receiver := g.File.ReduceTypeSrc(c.Type)
 	if receiver == nil {
-		log.Fatalf("cannot reduce type %s", c.Type)
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(c.Type))
 	}
 	srcSt := builder.NewFieldList(c.TagName)
 	_ = parser.InspectUnderlyingStruct(receiver, srcSt.SpreadInspector)
@@ -525,26 +1166,42 @@ func (g *Generator) Run(c *Config) {
 	rName := "p"
 
 	bd := builder.NewFuncBuffer(file, c.Name)
-	bd.Printf("func (%s *%s)%s() error {\n", rName, c.Type, c.Name)
+	// the receiver of a generic type is like Page[T]
+	recvExpr := parser.ExprToString(parser.TypeExprInFile(receiver, g.File))
+	ctx := ""
+	if c.Context {
+		ctx = "ctx"
+		bd.Printf("func (%s *%s)%s(ctx context.Context) error {\n", rName, recvExpr, c.Name)
+	} else {
+		bd.Printf("func (%s *%s)%s() error {\n", rName, recvExpr, c.Name)
+	}
 	bd.Printf("  if %s == nil {\n", rName)
 	bd.Printf("     return nil\n")
 	bd.Printf("  }\n")
 	bd.Printf("  chk := checker.NewParamChecker()\n")
 
+	type ruled struct {
+		sibling *sibling
+		ctags   string
+		pos     token.Position
+	}
+	siblings := []*sibling{}
+	fields := []*ruled{}
 	for _, srcFd := range srcSt.Fields {
 		tags := reflect.StructTag(srcFd.Field.Tag)
 		fieldName := srcFd.Field.Name()
 
 		ctags := tags.Get(c.TagName)
-		if ctags == "" {
-			continue
+		if ctags == "" && diveType(srcFd.Field.Type, c.TagName) != nil {
+			// the struct with its own rules is validated by its own function
+			ctags = "dive"
 		}
 
 		var name string
-		names := strings.Split(ctags, ",")
-		if len(names) > 1 {
-			name = names[0]
-			ctags = names[1]
+		// name,rules, the commas in the rules are kept
+		if i := strings.Index(ctags, ","); i >= 0 && !strings.ContainsAny(ctags[:i], ":;'") {
+			name = ctags[:i]
+			ctags = ctags[i+1:]
 		} else {
 			name = getJsonTagName(tags)
 			if name == "" {
@@ -552,29 +1209,89 @@ func (g *Generator) Run(c *Config) {
 			}
 		}
 
+		s := &sibling{
+			goName:     fieldName,
+			name:       name,
+			expr:       fmt.Sprintf("%s.%s", rName, fieldName),
+			targetType: srcFd.Field.Type,
+		}
+		siblings = append(siblings, s)
+		if ctags != "" {
+			fields = append(fields, &ruled{sibling: s, ctags: ctags, pos: srcFd.Field.Position()})
+		}
+	}
+
+	for _, fd := range fields {
 		info := &CheckerInfo{}
-		info.expr = fmt.Sprintf("%s.%s", rName, fieldName)
-		info.targetType = srcFd.Field.Type
+		info.expr = fd.sibling.expr
+		info.targetType = fd.sibling.targetType
 		info.chk = "chk"
-		info.name = fmt.Sprintf("\"%s\"", name)
+		info.name = fmt.Sprintf("\"%s\"", fd.sibling.name)
 		info.file = g.File
-
-		cc := NewChecker(info, bd, ctags)
-		cc.Next()
+		info.method = c.Name
+		info.siblings = siblings
+		info.ctx = ctx
+		info.failFast = c.FailFast
+
+		func() {
+			// the errors of the rules are at the field
+			defer errutil.CatchAt(fd.pos)
+			cc := NewChecker(info, bd, fd.ctags)
+			cc.Next()
+		}()
 		bd.Printf("\n")
 	}
 
 	bd.Printf("  return chk.GetError()\n")
 	bd.Printf("}\n")
 
-	log.Println(string(bd.Bytes()))
 	file.Add(bd)
 }
 
+// checkerImports returns the Imports with the checker package, unless it is in the Imports
+func checkerImports(c *Config) map[string]string {
+	imports := map[string]string{}
+	for name, path := range c.Imports {
+		imports[name] = path
+	}
+	if _, ok := imports["checker"]; !ok {
+		imports["checker"] = c.Checker
+		if c.Checker == "" {
+			imports["checker"] = DefaultChecker
+		}
+	}
+	return imports
+}
+
 func (g *Generator) Generate(c *Config) error {
-	g.Prepare(".", nil, c.Output)
-	g.PrepareImports(c.Imports)
-	g.Run(c)
-	g.Output(c.Output)
-	return nil
+	return g.GenerateAll([]*Config{c})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(configs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "checker") }()
+	defer errutil.Catch(&err)
+
+	if len(configs) == 0 {
+		return errors.New("no config given")
+	}
+	first := configs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, c := range configs {
+		if c.TagName == "" {
+			c.TagName = "checker"
+		}
+		if c.Name == "" {
+			c.Name = "Validate"
+		}
+		if err := g.PrepareImports(checkerImports(c)); err != nil {
+			return err
+		}
+		g.Own("checker " + c.Type + " " + c.Name)
+		g.Run(c)
+	}
+	return g.Output(first.Output)
 }
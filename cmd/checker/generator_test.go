@@ -2,8 +2,14 @@ package checker
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"testing"
 
+	"github.com/lawrsp/pigo/generator"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -378,7 +384,7 @@ func ExampleDefaultValueProc() {
 }
 
 func ExampleArrayProc() {
-	typ := parser.ParseTypeString("[]int")
+	typ, _ := parser.ParseTypeString("[]int")
 	ck := &CheckerInfo{
 		chk:        "mychecker",
 		name:       "\"hello\"",
@@ -396,7 +402,7 @@ func ExampleArrayProc() {
 	checker.procs = []CheckerProc{proc, NewDefaultValueProc(strings.Split("default:100", ":"))}
 	checker.Next()
 
-	typ = parser.ParseTypeString("[][]string")
+	typ, _ = parser.ParseTypeString("[][]string")
 	ck = &CheckerInfo{
 		chk:        "mychecker",
 		name:       "\"hello\"",
@@ -415,16 +421,263 @@ func ExampleArrayProc() {
 	checker.Next()
 
 	// output:
-	// for i, it := range t.Hello {
+	// for i := range t.Hello {
+	// it := t.Hello[i]
 	// if it == 0 {
 	// t.Hello[i] = 100
 	// }
 	// }
-	// for i, it := range t.Hello {
-	// for ii, itt := range it {
+	// for i := range t.Hello {
+	// it := t.Hello[i]
+	// for ii := range it {
+	// itt := it[ii]
 	// if itt == "" {
-	// it[ii] = "null"
+	// t.Hello[i][ii] = "null"
 	// }
 	// }
 	// }
 }
+
+func ExampleDiveProc() {
+	item := parser.TypeWithName(&parser.StructType{
+		Fields: []*parser.Field{parser.NewField(parser.NewBasicType("int"), "Price", `checker:"compare:>0"`)},
+	}, "Item")
+	for _, typ := range []parser.Type{
+		item,
+		&parser.ArrayType{Element: &parser.PointerType{Base: item, Stars: 1}, Slices: 1},
+	} {
+		ck := &CheckerInfo{
+			chk:        "mychecker",
+			name:       "\"items\"",
+			expr:       "t.Items",
+			targetType: typ,
+			method:     "Validate",
+		}
+		checker := &Checker{
+			c: ck,
+			p: &customPriter{},
+		}
+
+		// dive
+		// dive:other-valid-function
+		str := "dive"
+		proc := NewDiveProc(strings.Split(str, ":"))
+		checker.procs = []CheckerProc{proc}
+		checker.Next()
+	}
+
+	// output:
+	// if err := t.Items.Validate(); err != nil {
+	// mychecker.MergeField("items", err)
+	// }
+	// for i := range t.Items {
+	// if t.Items[i] != nil {
+	// if err := t.Items[i].Validate(); err != nil {
+	// mychecker.MergeField(fmt.Sprintf("items[%d]", i), err)
+	// }
+	// }
+	// }
+}
+
+func ExampleBoundProc() {
+	for _, rule := range []string{
+		"len:1:20",
+		"range::100:TooBig:too:big",
+		"oneof:a|b|c",
+		"regex:'^[a-z]+:\\d+$':BadCode",
+		"prefix:x-",
+		"datetime:'2006-01-02 15:04'",
+		"email",
+		"ip::invalid ip",
+	} {
+		ck := &CheckerInfo{
+			chk:        "mychecker",
+			name:       "\"hello\"",
+			expr:       "t.Hello",
+			targetType: parser.NewBasicType("string"),
+		}
+		checker := NewChecker(ck, &customPriter{}, rule)
+		checker.Next()
+	}
+
+	// output:
+	// mychecker.Assert(len(t.Hello) >= 1 && len(t.Hello) <= 20, "hello", "Invalid")
+	// mychecker.Assert(t.Hello <= 100, "hello", TooBig, "too", "big")
+	// mychecker.Assert(t.Hello == "a" || t.Hello == "b" || t.Hello == "c", "hello", "Invalid")
+	// mychecker.Assert(checker.MatchString("^[a-z]+:\\d+$", t.Hello), "hello", BadCode)
+	// mychecker.Assert(strings.HasPrefix(t.Hello, "x-"), "hello", "Invalid")
+	// mychecker.Assert(checker.IsDatetime("2006-01-02 15:04", t.Hello), "hello", "Invalid")
+	// mychecker.Assert(checker.IsEmail(t.Hello), "hello", "Invalid")
+	// mychecker.Assert(net.ParseIP(t.Hello) != nil, "hello", "Invalid", "invalid ip")
+}
+
+func ExampleFieldCompareProc() {
+	siblings := []*sibling{
+		{goName: "Password", name: "password", expr: "t.Password", targetType: parser.NewBasicType("string")},
+		{goName: "Type", name: "type", expr: "t.Type", targetType: parser.NewBasicType("string")},
+		{goName: "Card", name: "card", expr: "t.Card", targetType: parser.NewBasicType("string")},
+	}
+	for _, rule := range []string{
+		"eqfield:Password:Mismatch:not:same",
+		"required_if:type:card",
+		"required_with:Card",
+		"excluded_unless:Type:'card'",
+	} {
+		ck := &CheckerInfo{
+			chk:        "mychecker",
+			name:       "\"confirm\"",
+			expr:       "t.Confirm",
+			targetType: parser.NewBasicType("string"),
+			siblings:   siblings,
+		}
+		checker := NewChecker(ck, &customPriter{}, rule)
+		checker.Next()
+	}
+
+	// output:
+	// mychecker.AssertField(t.Confirm == t.Password, "confirm", "password", Mismatch, "not", "same")
+	// mychecker.AssertField(t.Type != "card" || !(t.Confirm == ""), "confirm", "type", "IsEmpty")
+	// mychecker.AssertField((t.Card == "") || !(t.Confirm == ""), "confirm", "card", "IsEmpty")
+	// mychecker.AssertField(t.Type == "card" || (t.Confirm == ""), "confirm", "type", "Invalid")
+}
+
+func ExampleChecker_failFast() {
+	item := parser.TypeWithName(&parser.StructType{
+		Fields: []*parser.Field{parser.NewField(parser.NewBasicType("int"), "Price", `checker:"compare:>0"`)},
+	}, "Item")
+	for _, c := range []struct {
+		rule string
+		typ  parser.Type
+	}{
+		{"noempty;len:2:", parser.NewBasicType("string")},
+		{"dive", &parser.PointerType{Base: item, Stars: 1}},
+	} {
+		ck := &CheckerInfo{
+			chk:        "mychecker",
+			name:       "\"hello\"",
+			expr:       "t.Hello",
+			targetType: c.typ,
+			method:     "Validate",
+			ctx:        "ctx",
+			failFast:   true,
+		}
+		checker := NewChecker(ck, &customPriter{}, c.rule)
+		checker.Next()
+	}
+
+	// output:
+	// mychecker.Assert(t.Hello != "", "hello", "IsEmpty")
+	// if err := mychecker.GetError(); err != nil {
+	// return err
+	// }
+	// mychecker.Assert(len(t.Hello) >= 2, "hello", "Invalid")
+	// if err := mychecker.GetError(); err != nil {
+	// return err
+	// }
+	// if t.Hello != nil {
+	// if err := t.Hello.Validate(ctx); err != nil {
+	// mychecker.MergeField("hello", err)
+	// return mychecker.GetError()
+	// }
+	// }
+}
+
+func TestSplitRule(t *testing.T) {
+	for _, c := range []struct {
+		src    string
+		sep    byte
+		expect []string
+	}{
+		{"stars:1;noempty", ';', []string{"stars:1", "noempty"}},
+		{"regex:'a:b;c':Code", ':', []string{"regex", "'a:b;c'", "Code"}},
+		{"regex:'a;b';noempty", ';', []string{"regex:'a;b'", "noempty"}},
+		{"noempty:::can't:be empty", ':', []string{"noempty", "", "", "can't", "be empty"}},
+		{"default:'it''s'", ':', []string{"default", "'it''s'"}},
+	} {
+		if got := splitRule(c.src, c.sep); !reflect.DeepEqual(got, c.expect) {
+			t.Errorf("split %q: expect %q, got %q", c.src, c.expect, got)
+		}
+	}
+}
+
+func TestCheckerImports(t *testing.T) {
+	for _, c := range []struct {
+		config *Config
+		expect map[string]string
+	}{
+		{&Config{}, map[string]string{"checker": DefaultChecker}},
+		{&Config{Checker: "example.com/chk"}, map[string]string{"checker": "example.com/chk"}},
+		{
+			&Config{Checker: "example.com/chk", Imports: map[string]string{"checker": "example.com/own", "time": "time"}},
+			map[string]string{"checker": "example.com/own", "time": "time"},
+		},
+	} {
+		if imports := checkerImports(c.config); !reflect.DeepEqual(imports, c.expect) {
+			t.Errorf("expect %v, got %v", c.expect, imports)
+		}
+	}
+}
+
+func TestGenerateErrorAtField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := "package a\n\ntype A struct {\n\tKind string `checker:\"oneof\"`\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGenerator()
+	g.Outputs = &generator.Outputs{}
+	err = g.Generate(&Config{Dir: dir, Type: "A", Output: filepath.Join(dir, "zz_checker.go")})
+	expect := filepath.Join(dir, "a.go") + ":4:2: oneof should have values"
+	if err == nil || !strings.HasSuffix(err.Error(), expect) {
+		t.Errorf("expect %q, got %v", expect, err)
+	}
+}
+
+// the rerun with the import of the rules keeps the output, so --check passes after generating
+func TestGenerateRerun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := "package a\n\ntype A struct {\n\tEmail string `checker:\"email\"`\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "zz_checker.go")
+	for i := 0; i < 2; i++ {
+		g := NewGenerator()
+		g.Header = generator.CodeHeader("1.0.0", "checker", "--type=A")
+		if err := g.Generate(&Config{Dir: dir, Type: "A", Output: output}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(result), "// Code generated by"); n != 1 {
+		t.Errorf("expect one header, got %d in:\n%s", n, result)
+	}
+
+	g := NewGenerator()
+	g.Header = generator.CodeHeader("1.0.0", "checker", "--type=A")
+	g.Check = true
+	if err := g.Generate(&Config{Dir: dir, Type: "A", Output: output}); err != nil {
+		t.Errorf("expect no drift, got %v", err)
+	}
+}
+
+func TestGenerateAllEmpty(t *testing.T) {
+	g := NewGenerator()
+	g.Outputs = &generator.Outputs{}
+	if err := g.GenerateAll(nil); err == nil || !strings.Contains(err.Error(), "no config given") {
+		t.Errorf("expect no config error, got %v", err)
+	}
+}
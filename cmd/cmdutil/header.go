@@ -0,0 +1,62 @@
+package cmdutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/urfave/cli"
+)
+
+// Header returns the generated code header of the running command
+// only the flags set are recorded, in the order they are declared
+func Header(c *cli.Context) string {
+	args := []string{}
+	for _, flag := range c.Command.Flags {
+		name := flagName(flag)
+		if name == "" || !c.IsSet(name) {
+			continue
+		}
+
+		switch flag.(type) {
+		case cli.BoolFlag:
+			args = append(args, "--"+name)
+		case cli.StringSliceFlag:
+			for _, v := range c.StringSlice(name) {
+				args = append(args, fmt.Sprintf("--%s=%s", name, normalizeArg(v)))
+			}
+		default:
+			args = append(args, fmt.Sprintf("--%s=%s", name, normalizeArg(c.String(name))))
+		}
+	}
+
+	for _, arg := range c.Args() {
+		args = append(args, normalizeArg(arg))
+	}
+
+	return generator.CodeHeader(c.App.Version, c.Command.Name, args...)
+}
+
+func flagName(flag cli.Flag) string {
+	names := strings.Split(flag.GetName(), ",")
+	return strings.TrimSpace(names[0])
+}
+
+// normalizeArg makes absolute paths relative to the working directory
+func normalizeArg(arg string) string {
+	if !filepath.IsAbs(arg) {
+		return arg
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return filepath.Base(arg)
+	}
+	rel, err := filepath.Rel(wd, arg)
+	if err != nil {
+		return filepath.Base(arg)
+	}
+	return filepath.ToSlash(rel)
+}
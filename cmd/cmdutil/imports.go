@@ -0,0 +1,21 @@
+package cmdutil
+
+import "strings"
+
+// Imports parses the --import values, "name:path" or "path",
+// the name of "path" is its last element
+func Imports(imports []string) map[string]string {
+	configImports := map[string]string{}
+	for _, impt := range imports {
+		kv := strings.Split(impt, ":")
+		if len(kv) == 1 {
+			path := kv[0]
+			names := strings.Split(path, "/")
+			name := names[len(names)-1]
+			configImports[name] = path
+		} else {
+			configImports[kv[0]] = kv[1]
+		}
+	}
+	return configImports
+}
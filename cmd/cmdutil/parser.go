@@ -0,0 +1,109 @@
+package cmdutil
+
+import (
+	"fmt"
+	"go/types"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator/parser"
+	"github.com/urfave/cli"
+)
+
+// NewLoader returns the parser.Loader of the --loader flag
+func NewLoader(name string) (parser.Loader, error) {
+	switch name {
+	case "", "build":
+		return parser.BuildLoader{}, nil
+	case "packages":
+		return parser.NewPackagesLoader(), nil
+	}
+	return nil, fmt.Errorf("unknown loader %s, should be build or packages", name)
+}
+
+// BuildFlags are the flags of the packages to parse, they are global and of every command,
+// so both `pigo --tags x checker` and `pigo checker --tags x` work
+var BuildFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "tags",
+		Usage: "a comma-separated list of build `TAGS` to consider satisfied when parsing the packages",
+	},
+	cli.StringFlag{
+		Name:  "goos",
+		Usage: "parse the packages for the target `GOOS`, the default is of the host",
+	},
+	cli.StringFlag{
+		Name:  "goarch",
+		Usage: "parse the packages for the target `GOARCH`, the default is of the host",
+	},
+}
+
+// buildFlag returns the build flag of the command, or the global one if it is not set
+func buildFlag(c *cli.Context, name string) string {
+	if c.IsSet(name) {
+		return c.String(name)
+	}
+	return c.GlobalString(name)
+}
+
+// Parser returns the parser of the running command, the global flags are checked by CheckFlags
+func Parser(c *cli.Context) *parser.Parser {
+	p := parser.NewParser()
+	p.Loader, _ = NewLoader(c.GlobalString("loader"))
+	SetBuildContext(p, BuildTags(buildFlag(c, "tags")), buildFlag(c, "goos"), buildFlag(c, "goarch"))
+	// the packages are parsed every time if there is no user cache directory
+	if !c.GlobalBool("no-cache") {
+		p.DiskCache, _ = parser.NewDiskCache()
+	}
+	return p
+}
+
+// BuildTags splits the --tags flag, the tags are separated by commas or spaces as the go command does
+func BuildTags(tags string) []string {
+	return strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// SetBuildContext sets the build tags and the target of the packages to parse,
+// empty goos or goarch keeps the default one
+func SetBuildContext(p *parser.Parser, tags []string, goos string, goarch string) {
+	ctx := &p.ImportContext
+	ctx.BuildTags = append(ctx.BuildTags, tags...)
+	if goos != "" {
+		ctx.GOOS = goos
+	}
+	if goarch != "" {
+		ctx.GOARCH = goarch
+	}
+	// cgo is disabled by default when cross compiling, as the go command does
+	if ctx.GOOS != runtime.GOOS || ctx.GOARCH != runtime.GOARCH {
+		ctx.CgoEnabled = false
+	}
+
+	loader, ok := p.Loader.(*parser.PackagesLoader)
+	if !ok {
+		return
+	}
+	if len(tags) > 0 {
+		loader.BuildFlags = append(loader.BuildFlags, "-tags="+strings.Join(tags, ","))
+	}
+	if goos != "" || goarch != "" {
+		if loader.Env == nil {
+			loader.Env = os.Environ()
+		}
+		loader.Env = append(loader.Env, "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH)
+	}
+}
+
+// CheckFlags checks the global flags, and the build flags of the command
+func CheckFlags(c *cli.Context) error {
+	if _, err := NewLoader(c.GlobalString("loader")); err != nil {
+		return err
+	}
+	if goarch := buildFlag(c, "goarch"); goarch != "" && types.SizesFor("gc", goarch) == nil {
+		return fmt.Errorf("unknown goarch %s", goarch)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package cmdutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+func TestBuildFlags(t *testing.T) {
+	for _, c := range []struct {
+		args   []string
+		tags   []string
+		goos   string
+		errMsg string
+	}{
+		{args: []string{"--tags", "x", "--goos", "plan9", "cmd"}, tags: []string{"x"}, goos: "plan9"},
+		{args: []string{"cmd", "--tags", "x,y", "--goos", "plan9"}, tags: []string{"x", "y"}, goos: "plan9"},
+		{args: []string{"--tags", "x", "--goos", "plan9", "cmd", "--tags", "y"}, tags: []string{"y"}, goos: "plan9"},
+		{args: []string{"cmd", "--goarch", "nope"}, errMsg: "unknown goarch nope"},
+	} {
+		var tags []string
+		var goos string
+		app := cli.NewApp()
+		app.Flags = append([]cli.Flag{cli.BoolFlag{Name: "no-cache"}}, BuildFlags...)
+		app.Before = CheckFlags
+		app.Commands = []cli.Command{{
+			Name:   "cmd",
+			Flags:  BuildFlags,
+			Before: CheckFlags,
+			Action: func(c *cli.Context) error {
+				p := Parser(c)
+				tags = p.ImportContext.BuildTags
+				goos = p.ImportContext.GOOS
+				return nil
+			},
+		}}
+
+		err := app.Run(append([]string{"pigo", "--no-cache"}, c.args...))
+		if c.errMsg != "" {
+			if err == nil || !strings.Contains(err.Error(), c.errMsg) {
+				t.Errorf("%v: expect error %q, got %v", c.args, c.errMsg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%v: %v", c.args, err)
+		}
+		if !reflect.DeepEqual(tags, c.tags) || goos != c.goos {
+			t.Errorf("%v: expect tags %v and goos %s, got %v and %s", c.args, c.tags, c.goos, tags, goos)
+		}
+	}
+}
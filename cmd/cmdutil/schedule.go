@@ -0,0 +1,163 @@
+package cmdutil
+
+import (
+	"go/build"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/lawrsp/pigo/generator"
+	"golang.org/x/tools/go/packages"
+)
+
+// Job is a generation run by Schedule
+type Job struct {
+	// Output is the file written by the job
+	Output string
+	// Reads are the package directories read by the job, see Deps,
+	// nil if not known, then the job conflicts with all
+	Reads []string
+	Run   func() error
+}
+
+// conflicts reports whether the jobs should run in order, one writes a package the other reads
+func (j *Job) conflicts(other *Job) bool {
+	output, otherOutput := absPath(j.Output), absPath(other.Output)
+	if output == otherOutput || j.Reads == nil || other.Reads == nil {
+		return true
+	}
+	return contains(other.Reads, filepath.Dir(output)) || contains(j.Reads, filepath.Dir(otherOutput))
+}
+
+func contains(dirs []string, dir string) bool {
+	for _, d := range dirs {
+		if absPath(d) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+func absPath(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+	return abs
+}
+
+// Schedule runs the jobs, at most n at once, runtime.GOMAXPROCS if n is 0,
+// a job waits for the jobs before it which conflict with it, so the outputs are as the jobs run in order,
+// and it is skipped if one of them fails or is skipped, the errors are returned in the order of the jobs
+func Schedule(n int, jobs []*Job) []error {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	errs := make([]error, len(jobs))
+	skipped := make([]bool, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range jobs {
+		done[i] = make(chan struct{})
+	}
+
+	slots := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		go func(i int, job *Job) {
+			defer wg.Done()
+			defer close(done[i])
+			for j := 0; j < i; j++ {
+				if !jobs[j].conflicts(job) {
+					continue
+				}
+				<-done[j]
+				if errs[j] != nil || skipped[j] {
+					skipped[i] = true
+					return
+				}
+			}
+
+			slots <- struct{}{}
+			errs[i] = job.Run()
+			<-slots
+		}(i, job)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Deps returns the package directories read by the generators in each of the directories,
+// the directory and the directories of the packages it imports, directly or not,
+// except the standard ones and the ones of the required modules, which are not changed,
+// the packages are listed at once by the go command in the first directory, as the build context of the parser
+func Deps(context build.Context, dirs []string) (map[string][]string, error) {
+	patterns := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		patterns = append(patterns, absPath(dir))
+	}
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Env:  append(os.Environ(), "GOOS="+context.GOOS, "GOARCH="+context.GOARCH),
+	}
+	if len(patterns) > 0 {
+		config.Dir = patterns[0]
+	}
+	if len(context.BuildTags) > 0 {
+		config.BuildFlags = []string{"-tags=" + strings.Join(context.BuildTags, ",")}
+	}
+	pkgs, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+
+	goroot := absPath(context.GOROOT) + string(filepath.Separator)
+	result := map[string][]string{}
+	for _, pkg := range pkgs {
+		dir := packageDir(pkg)
+		seen := map[string]bool{}
+		packages.Visit([]*packages.Package{pkg}, func(imported *packages.Package) bool {
+			d := packageDir(imported)
+			if d == "" || strings.HasPrefix(d, goroot) || required(imported) || seen[d] {
+				return false
+			}
+			seen[d] = true
+			return true
+		}, nil)
+		result[dir] = generator.SortedKeys(seen)
+	}
+	return result, nil
+}
+
+// SetReads sets the Reads of the jobs, the Deps of the packages of their outputs,
+// they are kept nil if the packages cannot be listed, so the jobs run in order
+func SetReads(context build.Context, jobs []*Job) {
+	dirs := []string{}
+	for _, job := range jobs {
+		dirs = append(dirs, filepath.Dir(absPath(job.Output)))
+	}
+	deps, err := Deps(context, dirs)
+	if err != nil {
+		return
+	}
+	for i, job := range jobs {
+		job.Reads = deps[dirs[i]]
+	}
+}
+
+// required reports whether the package is of a required module, except the ones replaced by a directory
+func required(pkg *packages.Package) bool {
+	m := pkg.Module
+	return m != nil && !m.Main && (m.Replace == nil || m.Replace.Version != "")
+}
+
+func packageDir(pkg *packages.Package) string {
+	for _, files := range [][]string{pkg.GoFiles, pkg.OtherFiles} {
+		if len(files) > 0 {
+			return filepath.Dir(files[0])
+		}
+	}
+	return ""
+}
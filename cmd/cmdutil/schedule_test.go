@@ -0,0 +1,109 @@
+package cmdutil
+
+import (
+	"errors"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	var mu sync.Mutex
+	order := []string{}
+	started := make(chan struct{})
+	job := func(name string, output string, reads []string, run func() error) *Job {
+		return &Job{Output: output, Reads: reads, Run: func() error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return run()
+		}}
+	}
+	ok := func() error { return nil }
+
+	jobs := []*Job{
+		// a and b are independent, they run at once
+		job("a", "/a/zz.go", []string{"/a"}, func() error {
+			select {
+			case started <- struct{}{}:
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("b not started")
+			}
+		}),
+		job("b", "/b/zz.go", []string{"/b"}, func() error {
+			select {
+			case <-started:
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("a not started")
+			}
+		}),
+		// c reads a, so it runs after a
+		job("c", "/c/zz.go", []string{"/a", "/c"}, ok),
+		// the reads of d are not known, it runs after all
+		job("d", "/d/zz.go", nil, ok),
+		// e writes b, which is read by b, and it fails
+		job("e", "/b/zz_e.go", []string{"/b"}, func() error { return errors.New("e failed") }),
+		// f reads b after e, it is skipped
+		job("f", "/f/zz.go", []string{"/b", "/f"}, ok),
+	}
+	errs := Schedule(4, jobs)
+	for i, err := range errs {
+		if (i == 4) != (err != nil) {
+			t.Errorf("job %d: unexpected error %v", i, err)
+		}
+	}
+
+	index := map[string]int{}
+	for i, name := range order {
+		index[name] = i
+	}
+	if _, ok := index["f"]; ok {
+		t.Error("f should be skipped")
+	}
+	for _, before := range [][2]string{{"a", "c"}, {"a", "d"}, {"b", "d"}, {"c", "d"}, {"d", "e"}} {
+		if index[before[0]] > index[before[1]] {
+			t.Errorf("%s should run before %s, got %v", before[0], before[1], order)
+		}
+	}
+}
+
+func TestDeps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	files := map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.18\n",
+		"a/a.go": "package a\n\nimport \"example.com/m/b\"\n\nvar _ b.B\n",
+		"b/b.go": "package b\n\nimport (\n\t\"strings\"\n\n\t\"example.com/m/c\"\n)\n\ntype B c.C\n\nvar _ = strings.Join\n",
+		"c/c.go": "package c\n\ntype C int\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, b, c := filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")
+	deps, err := Deps(build.Default, []string{a, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string][]string{a: {a, b, c}, c: {c}}
+	if !reflect.DeepEqual(deps, expect) {
+		t.Errorf("expect %v, got %v", expect, deps)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 
 	"github.com/lawrsp/pigo/generator/configutil"
@@ -72,6 +73,10 @@ func Action(c *cli.Context) error {
 			return fmt.Errorf("Version not supported")
 		}
 		g := NewGenerator()
+		g.Header = cmdutil.Header(c)
+		g.Check = c.GlobalBool("check")
+		g.Verify = c.GlobalBool("verify")
+		g.Parser = cmdutil.Parser(c)
 		return g.Generate(config)
 	}
 	/*
@@ -135,19 +140,7 @@ func Action(c *cli.Context) error {
 	}
 	config.Assigns = configAssigns
 
-	imports := c.StringSlice("import")
-	configImports := map[string]string{}
-	for _, impt := range imports {
-		kv := strings.Split(impt, ":")
-		if len(kv) == 1 {
-			path := kv[0]
-			names := strings.Split(path, "/")
-			name := names[len(names)-1]
-			configImports[name] = path
-		} else {
-			configImports[kv[0]] = kv[1]
-		}
-	}
+	configImports := cmdutil.Imports(c.StringSlice("import"))
 	config.Imports = configImports
 
 	tagname := c.String("tag")
@@ -155,5 +148,9 @@ func Action(c *cli.Context) error {
 
 	// jsonutil.Pretty(config, os.Stdout)
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 	return g.Generate(config)
 }
@@ -0,0 +1,60 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli"
+)
+
+// the convert of the flags parses the package with the global flags, as the one of --file
+func TestActionFlags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.go":   "package a\n\ntype A struct {\n\tName string\n}\n",
+		"b_x.go": "//go:build x\n\npackage a\n\ntype B struct {\n\tName string\n}\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{Name: "tags"},
+		cli.BoolFlag{Name: "no-cache"},
+	}
+	app.Commands = []cli.Command{
+		{Name: "convert", Flags: Flags, Action: Action},
+	}
+	args := []string{"pigo", "--no-cache", "--tags", "x", "convert", "-s", "A", "-t", "B", "-o", "zz_convert.go"}
+	if err := app.Run(args); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ioutil.ReadFile(filepath.Join(dir, "zz_convert.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "func convertAToB("; !strings.Contains(string(result), expect) {
+		t.Errorf("expect %q in:\n%s", expect, result)
+	}
+}
@@ -3,13 +3,13 @@ package convert
 import (
 	"fmt"
 
-	"log"
 	"strings"
 
 	"go/ast"
 
-	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -97,13 +97,17 @@ func NewStructInfo(origin string) *StructInfo {
 	//fs := token.NewFileSet()
 	expr, err := parser.ParseExpr(origin)
 	if err != nil {
-		log.Fatalf("parsing type: %s: %s", origin, err)
+		errutil.Throw(errutil.New("parsing type: %w", err).WithExpr(origin))
 		return nil
 	}
 
 	//_ = ast.Print(fs, expr)
 	//@CHECK:
-	st.Type = NewType(parser.ParseType(expr))
+	typ, err := parser.ParseType(expr)
+	if err != nil {
+		errutil.Throw(err)
+	}
+	st.Type = NewType(typ)
 	st.expr = expr
 
 	return st
@@ -125,6 +129,9 @@ type genTask struct {
 
 	Depend      string
 	SourceError ast.Expr
+
+	// source is the type in config, the owner of the function
+	source string
 }
 
 type Generator struct {
@@ -137,6 +144,8 @@ type Generator struct {
 	IgnoreImportPaths []string
 
 	taskes map[string]*genTask //naem : task
+	// order is the names of the taskes to generate in order
+	order []string
 
 	packageName string
 }
@@ -145,12 +154,11 @@ func NewGenerator() *Generator {
 	return &Generator{}
 }
 
+// PrepareImports adds the imports in the order of their names
 func (g *Generator) PrepareImports(imports map[string]string) {
 	var result []ImportLine
-	if imports != nil {
-		for k, v := range imports {
-			result = append(result, ImportLine{k, v})
-		}
+	for _, k := range generator.SortedKeys(imports) {
+		result = append(result, ImportLine{k, imports[k]})
 	}
 	g.Imports = result
 
@@ -163,58 +171,56 @@ func (g *Generator) PrepareImports(imports map[string]string) {
 	}
 }
 
+// PrepareAssigns reduces the assigns in the order of their names, the first matched one is used
 func (g *Generator) PrepareAssigns(assignConf map[string]YamlCustomAssign) {
 	assigns := []*CustomAssign{}
-	if assignConf != nil {
-		for _, v := range assignConf {
-			cp := &CustomAssign{}
-			cp.Source = g.ReduceTypeSrc(v.Source)
-			if cp.Source == nil {
-				log.Fatalf("type %s not reduced", v.Source)
-			}
-			cp.Target = g.ReduceTypeSrc(v.Target)
-			if cp.Target == nil {
-				log.Fatalf("type %s not reduced", v.Target)
-			}
-			cp.Assign = g.ReduceTypeSrc(v.Assign)
-			if cp.Assign == nil {
-				log.Fatalf("type %s not reduced", v.Assign)
-			}
-			cp.Check = v.Check
-			assigns = append(assigns, cp)
+	for _, k := range generator.SortedKeys(assignConf) {
+		v := assignConf[k]
+		cp := &CustomAssign{}
+		cp.Source = g.ReduceTypeSrc(v.Source)
+		if cp.Source == nil {
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Source))
 		}
+		cp.Target = g.ReduceTypeSrc(v.Target)
+		if cp.Target == nil {
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Target))
+		}
+		cp.Assign = g.ReduceTypeSrc(v.Assign)
+		if cp.Assign == nil {
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Assign))
+		}
+		cp.Check = v.Check
+		assigns = append(assigns, cp)
 	}
 
 	g.CustomAssigns = assigns
 }
 
+// PrepareTaskes prepares the tasks in the order of their names, a task after the one it depends on,
+// so the functions are generated in the same order every time
 func (g *Generator) PrepareTaskes(taskConf map[string]YamlTaskElem) {
-	taskes := map[string]*genTask{}
+	names := generator.SortedKeys(taskConf)
+
 	sorted := []string{}
-	needSort := [][2]string{}
-	for k, v := range taskConf {
-		if v.Depend == "" {
-			sorted = append(sorted, k)
-		} else {
-			a := [2]string{k, v.Depend}
-			needSort = append(needSort, a)
+	visited := map[string]bool{}
+	var visit func(k string)
+	visit = func(k string) {
+		if visited[k] {
+			return
 		}
-	}
-
-	for len(needSort) > 0 {
-		left := [][2]string{}
-		for i := 0; i < len(needSort); i++ {
-			for j := 0; j < len(needSort); j++ {
-				if needSort[i][1] == needSort[j][0] {
-					left = append(left, needSort[i])
-				} else {
-					sorted = append(sorted, needSort[i][0])
-				}
+		visited[k] = true
+		if depend := taskConf[k].Depend; depend != "" {
+			if _, ok := taskConf[depend]; ok {
+				visit(depend)
 			}
 		}
-		needSort = left
+		sorted = append(sorted, k)
+	}
+	for _, k := range names {
+		visit(k)
 	}
 
+	taskes := map[string]*genTask{}
 	for _, k := range sorted {
 		name := k
 		v := taskConf[k]
@@ -223,9 +229,11 @@ func (g *Generator) PrepareTaskes(taskConf map[string]YamlTaskElem) {
 		}
 
 		task := g.newTask(name, v.Source, v.Target, v.Depend, v.SourceError, v.WithoutError)
+		task.source = v.Source
 		taskes[k] = task
 	}
 	g.taskes = taskes
+	g.order = sorted
 }
 
 /*	g.PrepareInterface(yamlConf.Interface)
@@ -293,7 +301,13 @@ func (g *Generator) newTask(funcName string, src string, dst string, depend stri
 	task := genTask{}
 
 	task.Source = g.ReduceTypeSrc(src)
+	if task.Source == nil {
+		errutil.Throw(errutil.New("type not reduced").WithExpr(src))
+	}
 	task.Target = g.ReduceTypeSrc(dst)
+	if task.Target == nil {
+		errutil.Throw(errutil.New("type not reduced").WithExpr(dst))
+	}
 	fnt := &parser.FuncType{
 		Params: []*parser.Field{
 			parser.NewField(task.Source, "src", ""),
@@ -310,7 +324,7 @@ func (g *Generator) newTask(funcName string, src string, dst string, depend stri
 	if sourceError != "" {
 		expr, err := parser.ParseExpr(sourceError)
 		if err != nil {
-			log.Fatalf("source_error(%s) defined error: %v", sourceError, err)
+			errutil.Throw(errutil.New("source_error defined error: %w", err).WithExpr(sourceError))
 		}
 		task.SourceError = expr
 	}
@@ -358,6 +372,7 @@ func (tg *TaskGenerator) Run() bool {
 }
 
 func (g *Generator) generateTask(outer builder.Builder, task *genTask) builder.Builder {
+	defer errutil.CatchAt(parser.TypePosition(task.Source))
 	// log.Printf("%s", task.src.Type)
 	fb := builder.NewFunction(outer, nil, task.FuncType, nil)
 
@@ -375,7 +390,7 @@ func (g *Generator) generateTask(outer builder.Builder, task *genTask) builder.B
 			tp := parser.NewTPath(depended.Source, depended.Target).WithFunction(depended.FuncType)
 			tpaths = append(tpaths, tp)
 		} else {
-			log.Fatalf("cannot find depended %s", task.Depend)
+			errutil.Throwf("%s: cannot find depended %s", task.FuncType.Name(), task.Depend)
 		}
 	}
 
@@ -392,7 +407,7 @@ func (g *Generator) generateTask(outer builder.Builder, task *genTask) builder.B
 		return fb
 	}
 
-	log.Fatalf("cannot support %s to %s assign", task.Source, task.Target)
+	errutil.Throwf("%s: cannot support %s to %s assign", task.FuncType.Name(), task.Source, task.Target)
 	return nil
 }
 
@@ -435,7 +450,9 @@ func (g *Generator) Run() {
 		}
 	}
 
-	for _, t := range g.taskes {
+	for _, k := range g.order {
+		t := g.taskes[k]
+		g.Own("convert " + t.source + " " + t.FuncType.Name())
 		bd.Add(g.generateTask(bd, t))
 	}
 }
@@ -467,20 +484,27 @@ type YamlConfig struct {
 	Generates map[string]YamlTaskElem
 }
 
-func (g *Generator) Generate(yamlConf *YamlConfig) error {
+func (g *Generator) Generate(yamlConf *YamlConfig) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "convert") }()
+	defer errutil.Catch(&err)
+
 	if yamlConf.TagName != "" {
 		g.TagName = yamlConf.TagName
 	} else {
 		g.TagName = "pc"
 	}
 
-	g.Prepare(yamlConf.Dir, yamlConf.Files, yamlConf.Output)
+	if err := g.Prepare(yamlConf.Dir, yamlConf.Files, yamlConf.Output); err != nil {
+		return err
+	}
 	g.PrepareImports(yamlConf.Imports)
 	g.PrepareAssigns(yamlConf.Assigns)
 	g.PrepareTaskes(yamlConf.Generates)
 	g.Run()
 
-	g.Output(yamlConf.Output)
+	if err := g.Output(yamlConf.Output); err != nil {
+		return err
+	}
 
 	/*
 		fmt.Printf(string(g.Bytes()))
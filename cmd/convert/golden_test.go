@@ -0,0 +1,51 @@
+package convert
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	testutil.Run(t, "testdata", func(c *testutil.Case) error {
+		config := &YamlConfig{}
+		if err := c.ReadConfig(config); err != nil {
+			return err
+		}
+		config.Dir = c.Dir
+		config.Output = c.Path(config.Output)
+
+		g := NewGenerator()
+		g.Parser = c.Parser
+		g.Outputs = c.Outputs
+		return g.Generate(config)
+	})
+}
+
+func TestGenerateError(t *testing.T) {
+	dir := filepath.Join("testdata", "pointer")
+	for _, c := range []struct {
+		task   YamlTaskElem
+		expect string
+	}{
+		{YamlTaskElem{Source: "Nope", Target: "OrderView"}, "type not reduced (Nope)"},
+		{YamlTaskElem{Source: "Order", Target: "Nope"}, "type not reduced (Nope)"},
+		// the error is at the source type
+		{YamlTaskElem{Source: "Order", Target: "map[string]int"}, "pointer/model.go:3:6: toView: cannot support model.Order to map[string]int assign"},
+	} {
+		config := &YamlConfig{
+			Dir:       dir,
+			Output:    filepath.Join(dir, "zz_convert.go"),
+			Generates: map[string]YamlTaskElem{"toView": c.task},
+		}
+		g := NewGenerator()
+		g.Outputs = &generator.Outputs{}
+		err := g.Generate(config)
+		if err == nil || !strings.HasSuffix(err.Error(), c.expect) {
+			t.Errorf("expect %q, got %v", c.expect, err)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+type Item struct {
+	Name  string
+	Price *int
+}
+
+type ItemView struct {
+	Name  string
+	Price int
+}
+
+type Order struct {
+	ID    int64
+	Items []Item
+}
+
+type OrderView struct {
+	ID    int64
+	Items []ItemView
+}
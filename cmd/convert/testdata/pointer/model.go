@@ -0,0 +1,13 @@
+package model
+
+type Order struct {
+	ID    int64
+	Note  *string
+	Count *int
+}
+
+type OrderView struct {
+	ID    int64
+	Note  string
+	Count int
+}
@@ -1,6 +1,7 @@
 package evalid
 
 import (
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -43,6 +44,10 @@ func Action(c *cli.Context) error {
 	}
 
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 
 	return g.Generate(config)
 }
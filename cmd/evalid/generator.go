@@ -1,18 +1,20 @@
 package evalid
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/token"
-	"log"
 	"strings"
 
 	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
 type Config struct {
+	Dir    string
 	Const  string
 	Name   string
 	Output string
@@ -67,13 +69,22 @@ func (g *Generator) collectNames(decl *ast.GenDecl) bool {
 	return true
 }
 
+// FuncName returns the name of the function, IsXValid of the const prefix X by default
+func (g *Generator) FuncName() string {
+	if g.Name == "" {
+		return fmt.Sprintf("Is%sValid", g.NamePrefix)
+	}
+	return g.Name
+}
+
 func (g *Generator) PrepareTask(conf *Config) {
 	if conf.Const == "" {
-		log.Fatalf("no const name specified")
+		errutil.Throwf("no const name specified")
 	}
 	g.NamePrefix = conf.Const
 	g.Name = conf.Name
 	g.Type = conf.Type
+	g.NamesWithPos = map[string][]nameWithPos{}
 
 	if conf.Input != "" {
 		file := g.Pkg.GetFile(conf.Input)
@@ -113,11 +124,11 @@ func groupNamesByPos(names []nameWithPos) [][]string {
 	return groups
 }
 
+// groupNames groups the names of each file, in the order of the file names
 func groupNames(names map[string][]nameWithPos) [][]string {
 	groups := [][]string{}
-
-	for _, cns := range names {
-		groups = append(groups, groupNamesByPos(cns)...)
+	for _, name := range generator.SortedKeys(names) {
+		groups = append(groups, groupNamesByPos(names[name])...)
 	}
 	return groups
 }
@@ -140,10 +151,7 @@ func (g *Generator) Run() {
 	// }
 
 	file := builder.NewFile(nil, g.File)
-	name := g.Name
-	if name == "" {
-		name = fmt.Sprintf("Is%sValid", g.NamePrefix)
-	}
+	name := g.FuncName()
 
 	bd := builder.NewFuncBuffer(file, name)
 	bd.Printf("func %s(t %s) bool {\n", name, g.Type)
@@ -163,10 +171,27 @@ func (g *Generator) Run() {
 }
 
 func (g *Generator) Generate(conf *Config) error {
-	g.Prepare(".", nil, conf.Output)
-	g.PrepareTask(conf)
-	g.Run()
+	return g.GenerateAll([]*Config{conf})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(confs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "evalid") }()
+	defer errutil.Catch(&err)
+
+	if len(confs) == 0 {
+		return errors.New("no config given")
+	}
+	first := confs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, conf := range confs {
+		g.PrepareTask(conf)
+		g.Own("evalid " + conf.Type + " " + g.FuncName())
+		g.Run()
+	}
 
-	g.Output(conf.Output)
-	return nil
+	return g.Output(first.Output)
 }
@@ -3,6 +3,7 @@ package genrpc
 import (
 	"fmt"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/lawrsp/pigo/generator/configutil"
 	"github.com/urfave/cli"
 )
@@ -30,6 +31,10 @@ func Action(c *cli.Context) error {
 	}
 
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 
 	if err := g.Generate(config); err != nil {
 		return err
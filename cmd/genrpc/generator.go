@@ -3,15 +3,15 @@ package genrpc
 import (
 	"bytes"
 	"fmt"
-	"io/ioutil"
 	"regexp"
 
 	"go/ast"
 	"log"
-	"os"
 	"strings"
 
+	"github.com/lawrsp/pigo/generator"
 	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/nameutil"
 	"github.com/lawrsp/pigo/generator/parser"
 	"golang.org/x/tools/imports"
@@ -32,11 +32,20 @@ func (l ImportLine) String() string {
 
 type Generator struct {
 	buf    bytes.Buffer
-	parser *parser.Parser
+	Parser *parser.Parser
 	pkg    *parser.Package
 
 	File *parser.File
 
+	// Header is written on top of the output, see generator.CodeHeader
+	Header string
+	// Check only compares the result with the output, see generator.WriteOutput
+	Check bool
+	// Verify type checks the result before it is written, see generator.VerifyOutput
+	Verify bool
+	// Outputs keeps the result in memory if not nil, see generator.Outputs
+	Outputs *generator.Outputs
+
 	PackageName  string
 	Imports      []ImportLine
 	Receiver     *parser.DeclNode
@@ -110,25 +119,34 @@ func (g *Generator) AddImport(name string, path string) string {
 	return g.addImportWithOutCheck(name, path)
 }
 
+// PrepareParser creates the parser, unless a shared one is given
 func (g *Generator) PrepareParser() {
-	g.parser = parser.NewParser()
+	if g.Parser == nil {
+		g.Parser = parser.NewParser()
+	}
 }
 
+// PrepareImports adds the imports in the order of their names
 func (g *Generator) PrepareImports(imports map[string]string) {
-	if imports != nil {
-		for k, v := range imports {
-			g.AddImport(k, v)
-		}
+	for _, k := range generator.SortedKeys(imports) {
+		g.AddImport(k, imports[k])
 	}
 }
 
-func (g *Generator) PreparePackage(dir string, files []string) {
-	var p = g.parser
+func (g *Generator) PreparePackage(dir string, files []string) error {
+	var p = g.Parser
 	var pkg *parser.Package
+	var err error
 	if len(files) == 0 {
-		pkg = p.ParsePackageDir(dir)
+		if dir == "" {
+			dir = "."
+		}
+		pkg, err = p.ParsePackageDir(dir)
 	} else {
-		pkg = p.ParsePackageFiles(files)
+		pkg, err = p.ParsePackageFiles(files)
+	}
+	if err != nil {
+		return err
 	}
 
 	// add custom imports
@@ -141,7 +159,10 @@ func (g *Generator) PreparePackage(dir string, files []string) {
 		}
 		fmt.Fprintf(&buf, ")\n")
 		// fmt.Println(string(buf.Bytes()))
-		file := p.ParseFileContent("_custom", buf.Bytes())
+		file, err := p.ParseFileContent("_custom", buf.Bytes())
+		if err != nil {
+			return err
+		}
 		// printutil.PrintNodef(file, "_custom file is :")
 		p.InsertFileToPackage(pkg, file, 0)
 		g.File = file
@@ -149,6 +170,7 @@ func (g *Generator) PreparePackage(dir string, files []string) {
 
 	g.pkg = pkg
 	g.PackageName = pkg.Name
+	return nil
 }
 
 func (g *Generator) PrepareInterface(name string) {
@@ -168,18 +190,18 @@ func (g *Generator) PrepareInterface(name string) {
 	*/
 	expr, err := parser.ParseExpr(name)
 	if err != nil {
-		log.Fatalf("interface %s defination error: %v", name, err)
+		errutil.Throw(errutil.New("interface defination error: %w", err).WithExpr(name))
 	}
 
 	_, typ := g.pkg.ReduceType(expr)
 	if typ == nil {
-		log.Fatalf("cannot reduce interface type: %s", name)
+		errutil.Throw(errutil.New("cannot reduce interface type").WithExpr(name))
 	}
 
 	if _, ok := typ.Underlying().(*parser.InterfaceType); ok {
 		g.InterfaceType = typ
 	} else {
-		log.Fatalf("%s is not interface type", name)
+		errutil.Throwf("%s is not interface type", name)
 	}
 
 	// printutil.PrintNodef(itft, "filled:")
@@ -191,10 +213,10 @@ func (g *Generator) PrepareReceiver(name string) {
 	}
 	expr, err := parser.ParseExpr(name)
 	if err != nil {
-		log.Fatalf("receiver defination error: %s", name)
+		errutil.Throw(errutil.New("receiver defination error: %w", err).WithExpr(name))
 	}
 	if _, g.ReceiverType = g.pkg.ReduceType(expr); g.ReceiverType == nil {
-		log.Fatalf("cannot reduct receiver type: %s", name)
+		errutil.Throw(errutil.New("cannot reduce receiver type").WithExpr(name))
 	}
 
 	// g.pkg.Resolve(ReceiverType)
@@ -218,14 +240,15 @@ func (g *Generator) PrepareFunctions(names map[string]string) {
 	}
 
 	fns := map[string]parser.Type{}
-	for name, def := range names {
+	for _, name := range generator.SortedKeys(names) {
+		def := names[name]
 		expr, err := parser.ParseExpr(def)
 		if err != nil {
-			log.Fatalf("function defination %s error: %v", def, err)
+			errutil.Throw(errutil.New("function %s defination error: %w", name, err).WithExpr(def))
 		}
 		_, ft := g.pkg.ReduceType(expr)
 		if ft == nil {
-			log.Fatalf("cannot find function %v", name)
+			errutil.Throw(errutil.New("cannot find function %s", name).WithExpr(def))
 		}
 		log.Printf("function %s", ft)
 		fns[name] = parser.TypeWithExpr(ft, expr)
@@ -291,12 +314,15 @@ func (g *Generator) PrepareService(name string) {
 		srv = pkg.FindDecl(name, finder)
 		if srv != nil {
 			g.ServiceReceiver = srv
-			typ := parser.ParseType(srv.Node)
+			typ, err := parser.ParseType(srv.Node)
+			if err != nil {
+				errutil.Throw(err)
+			}
 			g.ServiceReceiverType = typ.String()
 		}
 	}
 	if srv == nil {
-		log.Fatalf("cannot find Service Recevier: %v", name)
+		errutil.Throwf("cannot find Service Recevier: %v", name)
 	}
 
 	g.ServicePackage = srv.File.BelongTo
@@ -349,12 +375,12 @@ func (g *Generator) getFunction(name string) parser.Type {
 
 	expr, err := parser.ParseExpr(name)
 	if err != nil {
-		log.Fatalf("function defination %s error: %v", name, err)
+		errutil.Throw(errutil.New("function defination error: %w", err).WithExpr(name))
 	}
 
 	_, fnt := g.pkg.ReduceType(expr)
 	if fnt == nil {
-		log.Fatalf("function %s not found", name)
+		errutil.Throw(errutil.New("function not found").WithExpr(name))
 	}
 
 	return parser.TypeWithExpr(fnt, expr)
@@ -372,7 +398,7 @@ func (g *Generator) addTask(name string, desc *TaskDesc) {
 
 	for _, t := range g.Taskes {
 		if t.Name == name {
-			log.Fatalf("repeated function name")
+			errutil.Throwf("repeated function name %s", name)
 		}
 	}
 
@@ -390,7 +416,7 @@ func (g *Generator) addTask(name string, desc *TaskDesc) {
 
 	fnt := parser.GetInterfaceFuncByName(g.InterfaceType, rpcFuncName)
 	if fnt == nil {
-		log.Fatalf("cannot found rpc function definiation: %s", rpcFuncName)
+		errutil.Throwf("cannot found rpc function definiation: %s", rpcFuncName)
 	}
 	task.RpcFunction = fnt
 
@@ -398,7 +424,7 @@ func (g *Generator) addTask(name string, desc *TaskDesc) {
 	if len(desc.ErrorWrapper) > 0 {
 		fn := g.Functions[desc.ErrorWrapper]
 		if fn == nil {
-			log.Fatalf("error function not found %s", desc.ErrorWrapper)
+			errutil.Throwf("error function not found %s", desc.ErrorWrapper)
 		}
 		task.ErrorWrapper = fn
 	}
@@ -422,8 +448,10 @@ func (g *Generator) addTask(name string, desc *TaskDesc) {
 
 }
 
+// PrepareTaskes adds the tasks in the order of their names
 func (g *Generator) PrepareTaskes(taskes map[string]TaskDesc) {
-	for k, v := range taskes {
+	for _, k := range generator.SortedKeys(taskes) {
+		v := taskes[k]
 		name := k
 		if len(v.Name) > 0 {
 			name = v.Name
@@ -473,12 +501,14 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 		if len(seq.Error) > 0 {
 			errExpr, err = parser.ParseExpr(seq.Error)
 			if err != nil {
-				log.Fatalf("check param error defination %s error: \n %v", seq.Error, err)
+				errutil.Throw(errutil.New("%s: check param error defination error: %w", task.Name, err).WithExpr(seq.Error))
 			}
 		}
 
 		vl := builder.NewVariableList()
 		namedParams := map[string]*builder.Variable{}
+		// the names of namedParams in the order of the params
+		paramNames := []string{}
 		if len(seq.Params) > 0 {
 			for _, param := range seq.Params {
 				exprSrc := param.Expr
@@ -487,21 +517,25 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 				}
 				var t parser.Type
 				if param.Type != "" {
-					t = parser.ParseTypeString(param.Type)
+					t, err = parser.ParseTypeString(param.Type)
 				} else {
-					t = parser.ParseTypeString(exprSrc)
+					t, err = parser.ParseTypeString(exprSrc)
+				}
+				if err != nil {
+					errutil.Throwf("%s: cannot decide param %s type: %w", task.Name, param.Name, err)
 				}
 				if t == nil {
-					log.Fatalf("cannot decied param type: %v", param)
+					errutil.Throwf("%s: cannot decide param %s type: %v", task.Name, param.Name, param)
 				}
 
 				v := builder.NewVariable(t).WithName(param.Name).WithMode(builder.READ_MODE)
 				var valueExpr ast.Expr
 				if valueExpr, err = parser.ParseExpr(exprSrc); err != nil {
-					log.Fatalf("cannot parser value expr")
+					errutil.Throw(errutil.New("%s: cannot parse value expr: %w", task.Name, err).WithExpr(exprSrc))
 				}
 				if v.Name() != "" {
 					namedParams[param.Name] = builder.AddVariableAssign(bd, v, valueExpr)
+					paramNames = append(paramNames, param.Name)
 				} else {
 					v.SetExpr(valueExpr)
 				}
@@ -516,7 +550,7 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 				exprStr := fmt.Sprintf(chk, topNames...)
 				expr, err := parser.ParseExpr(exprStr)
 				if err != nil {
-					log.Fatalf("check param defination %s error:\n %v", chk, err)
+					errutil.Throw(errutil.New("%s: check param defination error: %w", task.Name, err).WithExpr(exprStr))
 				}
 
 				ev := builder.NewVariable(parser.ErrorType()).WithExpr(errExpr).ReadOnly()
@@ -532,16 +566,19 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 				st := strings.Split(asn, "=")
 				lhs = st[0]
 				rhs = st[1]
-				rhsType := parser.ParseTypeString(rhs)
+				rhsType, err := parser.ParseTypeString(rhs)
+				if err != nil {
+					errutil.Throw(err)
+				}
 				lhsVar := builder.NewVariable(rhsType)
 
 				if strings.Contains(lhs, "%[") {
 					lhs = fmt.Sprintf(lhs, topNames...)
 				}
 
-				for name, v := range namedParams {
+				for _, name := range paramNames {
 					if strings.Contains(lhs, name) {
-						lhs = strings.Replace(lhs, name, v.Name(), -1)
+						lhs = strings.Replace(lhs, name, namedParams[name].Name(), -1)
 					}
 				}
 
@@ -562,13 +599,16 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 				receiver = bd.Receiver()
 			} else {
 				t := g.File.ReduceTypeSrc(desc.Receiver)
+				if t == nil {
+					errutil.Throw(errutil.New("%s: cannot reduce type", task.Name).WithExpr(desc.Receiver))
+				}
 				receiver = builder.GetVariable(bd.Block(), t, builder.READ_MODE, builder.Scope_Function)
 			}
 
 			rcallExpr := receiver.DotExpr(desc.Func)
 			rcallType := receiver.DotTypeInFile(desc.Func, g.File)
 			if rcallType == nil {
-				log.Fatalf("cannot reduce type %s", desc.Func)
+				errutil.Throw(errutil.New("%s: cannot reduce type", task.Name).WithExpr(desc.Func))
 			}
 			callFunc = parser.TypeWithExpr(rcallType, rcallExpr)
 			log.Printf("call func: %s", rcallType)
@@ -591,8 +631,6 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 
 	builder.AddSuccessReturn(bd)
 
-	fmt.Println(string(bd.Bytes()))
-
 	g.Printf("%s\n", string(bd.Bytes()))
 
 	return
@@ -601,8 +639,11 @@ func (g *Generator) generateTask(outer builder.Builder, task *Task) {
 // generate produces the function
 func (g *Generator) Run() {
 	// Print the header and package clause.
-	g.Printf("// Code generated by \"%s\"; DO NOT EDIT.\n", strings.Join(os.Args[0:], " "))
-	g.Printf("\n")
+	header := g.Header
+	if header == "" {
+		header = generator.CodeHeader("", "genrpc")
+	}
+	g.Printf("%s\n", header)
 	g.Printf("package %s \n", g.PackageName)
 	g.Printf("\n")
 
@@ -676,11 +717,15 @@ type YamlConfig struct {
 	Functions map[string]string
 }
 
-func (g *Generator) Generate(yamlConf *YamlConfig) error {
+func (g *Generator) Generate(yamlConf *YamlConfig) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "genrpc") }()
+	defer errutil.Catch(&err)
 
 	g.PrepareParser()
 	g.PrepareImports(yamlConf.Imports)
-	g.PreparePackage(yamlConf.Dir, yamlConf.Files)
+	if err := g.PreparePackage(yamlConf.Dir, yamlConf.Files); err != nil {
+		return err
+	}
 	g.PrepareInterface(yamlConf.Interface)
 	g.PrepareReceiver(yamlConf.Receiver)
 	g.PrepareFunctions(yamlConf.Functions)
@@ -692,18 +737,17 @@ func (g *Generator) Generate(yamlConf *YamlConfig) error {
 	// Format the output.
 	result, err := g.Format()
 	if err != nil {
-		log.Fatalf("Foramt Failed: %v", err)
+		return fmt.Errorf("format failed: %w", err)
 	}
 
-	// Write to stdout / file
-	if len(yamlConf.Output) == 0 {
-		fmt.Printf(string(result))
-	} else {
-		err = ioutil.WriteFile(yamlConf.Output, result, 0644)
-		if err != nil {
-			log.Fatalf("writing output: %s", err)
+	if g.Verify {
+		if err := generator.VerifyOutput(g.Parser, yamlConf.Output, result); err != nil {
+			return err
 		}
 	}
-
-	return nil
+	if g.Outputs != nil {
+		return g.Outputs.Write(g.Parser, yamlConf.Output, result)
+	}
+	// Write to stdout / file
+	return generator.WriteOutput(yamlConf.Output, result, g.Check)
 }
@@ -0,0 +1,90 @@
+package inspect
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/urfave/cli"
+)
+
+var Usage = "print the type resolved by the parser"
+var Description = `print the fields and methods of the type, as the generators see it, e.g.
+
+   pigo inspect --type User --json
+   pigo inspect --type example.com/project/model.User
+
+the fields of the embedded structs follow the embedded one, with the path from the type,
+a type of "path/to/pkg.Name" is imported from the path`
+
+var Flags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "type,t",
+		Usage: "the `TYPE` to inspect",
+	},
+	cli.StringSliceFlag{
+		Name:  "import,i",
+		Usage: "the requried imports",
+	},
+	cli.BoolFlag{
+		Name:  "json",
+		Usage: "print the type as JSON",
+	},
+}
+
+func Action(c *cli.Context) error {
+	t := c.String("type")
+	if t == "" {
+		return fmt.Errorf("type should be given")
+	}
+	config := &Config{
+		Type:    t,
+		Imports: cmdutil.Imports(c.StringSlice("import")),
+	}
+
+	g := NewGenerator()
+	g.Parser = cmdutil.Parser(c)
+	info, err := g.Inspect(config)
+	if err != nil {
+		return err
+	}
+	if c.Bool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	return Print(os.Stdout, info)
+}
+
+// Print writes the type in columns
+func Print(w io.Writer, info *TypeInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", info.Type, info.Underlying, info.Package)
+	for _, f := range info.Fields {
+		embedded := ""
+		if f.Embedded {
+			embedded = "embedded"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", f.Path, f.Type, f.Package, embedded, f.Tag)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, m := range info.Methods {
+		receiver := info.Name
+		if m.Pointer {
+			receiver = "*" + receiver
+		}
+		promoted := ""
+		if m.Promoted != "" {
+			promoted = "promoted from " + m.Promoted
+		}
+		fmt.Fprintf(tw, "  func (%s) %s%s\t%s\n", receiver, m.Name, m.Signature, promoted)
+	}
+	return tw.Flush()
+}
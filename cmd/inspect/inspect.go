@@ -0,0 +1,184 @@
+package inspect
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+type Config struct {
+	Dir     string
+	Type    string
+	Imports map[string]string
+}
+
+// TypeInfo is the resolved type, the types are written as parser.Type, qualified by the package names
+type TypeInfo struct {
+	Name string `json:"name"`
+	// Package is the import path of the package declaring the type
+	Package    string        `json:"package,omitempty"`
+	Type       string        `json:"type"`
+	Underlying string        `json:"underlying"`
+	Fields     []*FieldInfo  `json:"fields,omitempty"`
+	Methods    []*MethodInfo `json:"methods,omitempty"`
+}
+
+// FieldInfo is a field, the fields of the embedded structs follow the embedded one
+type FieldInfo struct {
+	Name string `json:"name"`
+	// Path is the selector from the type, e.g. Base.ID of a field of the embedded Base
+	Path       string `json:"path"`
+	Type       string `json:"type"`
+	Underlying string `json:"underlying"`
+	// Package is the import path of the package declaring the type
+	Package  string            `json:"package,omitempty"`
+	Stars    int               `json:"stars,omitempty"`
+	Embedded bool              `json:"embedded,omitempty"`
+	Tag      string            `json:"tag,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// MethodInfo is a method declared on the type, or promoted from an embedded field
+type MethodInfo struct {
+	Name string `json:"name"`
+	// Pointer is true if the receiver is a pointer
+	Pointer   bool   `json:"pointer,omitempty"`
+	Signature string `json:"signature"`
+	// Promoted is the path of the embedded field declaring the method
+	Promoted string `json:"promoted,omitempty"`
+}
+
+type Generator struct {
+	generator.Generator
+}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+// TypeSrc returns the type expression of src and its import,
+// a type of "path/to/pkg.Name" is imported from the path, as "pkg.Name"
+func TypeSrc(src string) (string, map[string]string) {
+	dot := strings.LastIndex(src, ".")
+	if dot < 0 || !strings.Contains(src[:dot], "/") {
+		return src, nil
+	}
+	path := strings.TrimLeft(src[:dot], "*[]")
+	prefix := src[:len(src[:dot])-len(path)]
+	name := path[strings.LastIndex(path, "/")+1:]
+	return prefix + name + src[dot:], map[string]string{name: path}
+}
+
+// Inspect resolves the type in the package of the config
+func (g *Generator) Inspect(c *Config) (info *TypeInfo, err error) {
+	defer func() { err = errutil.WithGenerator(err, "inspect") }()
+	defer errutil.Catch(&err)
+
+	src, imports := TypeSrc(c.Type)
+	if err := g.Prepare(c.Dir, nil, ""); err != nil {
+		return nil, err
+	}
+	if err := g.PrepareImports(c.Imports); err != nil {
+		return nil, err
+	}
+	if err := g.PrepareImports(imports); err != nil {
+		return nil, err
+	}
+
+	t := g.ReduceTypeSrc(src)
+	if t == nil {
+		errutil.Throw(errutil.New("type not reduced").WithExpr(src))
+	}
+	info = &TypeInfo{
+		Name:       src,
+		Package:    packagePath(t),
+		Type:       t.String(),
+		Underlying: t.Underlying().String(),
+	}
+	g.addFields(info, t, "", map[string]bool{})
+	return info, nil
+}
+
+func (g *Generator) addFields(info *TypeInfo, t parser.Type, prefix string, seen map[string]bool) {
+	// an embedded *T in T
+	key := strings.TrimLeft(t.String(), "*")
+	if seen[key] {
+		return
+	}
+	seen[key] = true
+	defer delete(seen, key)
+
+	g.addMethods(info, t, strings.TrimSuffix(prefix, "."))
+	parser.InspectUnderlyingStruct(t, func(fd *parser.Field) bool {
+		path := prefix + fd.Name()
+		info.Fields = append(info.Fields, &FieldInfo{
+			Name:       fd.Name(),
+			Path:       path,
+			Type:       fd.Type.String(),
+			Underlying: fd.Type.Underlying().String(),
+			Package:    packagePath(fd.Type),
+			Stars:      parser.GetTypeStars(fd.Type),
+			Embedded:   fd.IsAnonymous(),
+			Tag:        fd.Tag,
+			Tags:       parseTag(fd.Tag),
+		})
+		if fd.IsAnonymous() {
+			g.addFields(info, fd.Type, path+".", seen)
+		}
+		return false
+	})
+}
+
+func (g *Generator) addMethods(info *TypeInfo, t parser.Type, promoted string) {
+	for _, fd := range parser.Methods(t) {
+		_, pointer := fd.Recv.List[0].Type.(*ast.StarExpr)
+		var signature bytes.Buffer
+		if err := format.Node(&signature, g.Parser.FileSet, fd.Type); err != nil {
+			errutil.Throw(err)
+		}
+		info.Methods = append(info.Methods, &MethodInfo{
+			Name:      fd.Name.Name,
+			Pointer:   pointer,
+			Signature: strings.TrimPrefix(signature.String(), "func"),
+			Promoted:  promoted,
+		})
+	}
+}
+
+// packagePath returns the import path of the package declaring t, or *t, []t
+func packagePath(t parser.Type) string {
+	if file := t.File(); file != nil && file.BelongTo != nil {
+		return file.BelongTo.Path
+	}
+	return ""
+}
+
+// parseTag returns the values of a struct tag by key
+func parseTag(tag string) map[string]string {
+	if tag == "" {
+		return nil
+	}
+	tags := map[string]string{}
+	for tag != "" {
+		tag = strings.TrimLeft(tag, " ")
+		colon := strings.Index(tag, ":")
+		if colon <= 0 || colon+1 >= len(tag) || tag[colon+1] != '"' {
+			break
+		}
+		key := tag[:colon]
+		value, err := strconv.QuotedPrefix(tag[colon+1:])
+		if err != nil {
+			break
+		}
+		tag = tag[colon+1+len(value):]
+		tags[key] = reflect.StructTag(key + ":" + value).Get(key)
+	}
+	return tags
+}
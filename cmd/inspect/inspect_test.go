@@ -0,0 +1,91 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+func TestTypeSrc(t *testing.T) {
+	cases := []struct {
+		src     string
+		expr    string
+		imports map[string]string
+	}{
+		{"User", "User", nil},
+		{"model.User", "model.User", nil},
+		{"example.com/project/model.User", "model.User", map[string]string{"model": "example.com/project/model"}},
+		{"*[]example.com/model.User", "*[]model.User", map[string]string{"model": "example.com/model"}},
+	}
+	for _, c := range cases {
+		expr, imports := TypeSrc(c.src)
+		if expr != c.expr || !reflect.DeepEqual(imports, c.imports) {
+			t.Errorf("%s: expect %s %v, got %s %v", c.src, c.expr, c.imports, expr, imports)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	g := NewGenerator()
+	g.Parser = parser.NewParser()
+	info, err := g.Inspect(&Config{Dir: "testdata/model", Type: "User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{}
+	for _, f := range info.Fields {
+		paths = append(paths, f.Path)
+	}
+	expect := []string{"Base", "Base.ID", "Base.CreatedAt", "Name", "Parent", "Tags"}
+	if !reflect.DeepEqual(paths, expect) {
+		t.Errorf("expect fields %v, got %v", expect, paths)
+	}
+
+	id := info.Fields[1]
+	if id.Type != "int" || !reflect.DeepEqual(id.Tags, map[string]string{"json": "id", "db": "id,pk"}) {
+		t.Errorf("unexpected %+v", id)
+	}
+	createdAt := info.Fields[2]
+	if createdAt.Type != "time.Time" || createdAt.Package != "time" || createdAt.Underlying != "struct" {
+		t.Errorf("unexpected %+v", createdAt)
+	}
+	if parent := info.Fields[4]; parent.Stars != 1 || parent.Embedded {
+		t.Errorf("unexpected %+v", parent)
+	}
+
+	methods := []MethodInfo{}
+	for _, m := range info.Methods {
+		methods = append(methods, *m)
+	}
+	expectMethods := []MethodInfo{
+		{Name: "String", Signature: "() string"},
+		{Name: "Key", Pointer: true, Signature: "() int", Promoted: "Base"},
+	}
+	if !reflect.DeepEqual(methods, expectMethods) {
+		t.Errorf("expect methods %+v, got %+v", expectMethods, methods)
+	}
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(info); err != nil {
+		t.Fatal(err)
+	}
+	if err := Print(&buf, info); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInspectEmbeddedSelf(t *testing.T) {
+	g := NewGenerator()
+	g.Parser = parser.NewParser()
+	info, err := g.Inspect(&Config{Dir: "testdata/model", Type: "Node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Fields) != 2 {
+		t.Errorf("expect Node and Name only, got %d fields", len(info.Fields))
+	}
+}
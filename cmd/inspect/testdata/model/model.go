@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+type Base struct {
+	ID        int `json:"id" db:"id,pk"`
+	CreatedAt time.Time
+}
+
+func (b *Base) Key() int {
+	return b.ID
+}
+
+type Node struct {
+	*Node
+	Name string `json:"name,omitempty"`
+}
+
+type User struct {
+	Base
+	Name   string `json:"name"`
+	Parent *Node
+	Tags   []string
+}
+
+func (u User) String() string {
+	return u.Name
+}
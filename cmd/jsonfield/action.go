@@ -3,6 +3,7 @@ package jsonfield
 import (
 	"log"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -40,6 +41,10 @@ func Action(c *cli.Context) error {
 		Name:    name,
 	}
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 
 	log.Printf("start jsonfield:")
 	return g.Generate(config)
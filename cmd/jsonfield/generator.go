@@ -1,16 +1,19 @@
 package jsonfield
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 
 	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 	"github.com/lawrsp/stringstyles"
 )
 
 type Config struct {
+	Dir     string
 	Type    string
 	Name    string
 	Output  string
@@ -32,7 +35,11 @@ func NewGenerator() *Generator {
 func (g *Generator) PrepareTask(c *Config) {
 	g.TagName = c.TagName
 
-	g.Receiver = parser.TypeWithPointer(g.File.ReduceTypeSrc(c.Type))
+	receiver := g.File.ReduceTypeSrc(c.Type)
+	if receiver == nil {
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(c.Type))
+	}
+	g.Receiver = parser.TypeWithPointer(receiver)
 	g.ResultType = parser.MapType(parser.NewBasicType("string"), parser.NewBasicType("interface"))
 
 	funcName := c.Name
@@ -113,9 +120,26 @@ func (g *Generator) Run() {
 }
 
 func (g *Generator) Generate(c *Config) error {
-	g.Prepare(".", nil, c.Output)
-	g.PrepareTask(c)
-	g.Run()
-	g.Output(c.Output)
-	return nil
+	return g.GenerateAll([]*Config{c})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(configs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "jsonfield") }()
+	defer errutil.Catch(&err)
+
+	if len(configs) == 0 {
+		return errors.New("no config given")
+	}
+	first := configs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, c := range configs {
+		g.PrepareTask(c)
+		g.Own("jsonfield " + c.Type + " " + g.FuncType.Name())
+		g.Run()
+	}
+	return g.Output(first.Output)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -36,13 +37,7 @@ func Action(c *cli.Context) error {
 	}
 
 	tag := c.String("tag")
-	if tag == "" {
-		tag = "pfilter"
-	}
 	name := c.String("name")
-	if name == "" {
-		name = "FilterByPaths"
-	}
 
 	workfile := c.Args().Get(0)
 
@@ -56,6 +51,10 @@ func Action(c *cli.Context) error {
 	}
 
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 	log.Printf("start generate pfilter %s:", t)
 	return g.Generate(config)
 }
@@ -1,6 +1,7 @@
 package pfilter
 
 import (
+	"errors"
 	"go/ast"
 	"log"
 	"reflect"
@@ -8,10 +9,12 @@ import (
 
 	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
 type Config struct {
+	Dir      string
 	Type     string
 	Name     string
 	Output   string
@@ -42,11 +45,16 @@ func (g *Generator) Run(c *Config) {
 
 	file := builder.NewFile(nil, g.File)
 	receiverType := g.ReduceTypeSrc(c.Type)
+	if receiverType == nil {
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(c.Type))
+	}
 
 	rName := "p"
 
 	bd := builder.NewFuncBuffer(file, c.Name)
-	bd.Printf("func (%s *%s)%s(paths []string)  {\n", rName, c.Type, c.Name)
+	// the receiver of a generic type is like Page[T]
+	recvExpr := parser.ExprToString(parser.TypeExprInFile(receiverType, g.File))
+	bd.Printf("func (%s *%s)%s(paths []string)  {\n", rName, recvExpr, c.Name)
 
 	bd.Printf("if paths == nil || len(paths) == 0 {\n")
 	bd.Printf("return")
@@ -113,12 +121,36 @@ func (g *Generator) Run(c *Config) {
 }
 
 func (g *Generator) Generate(c *Config) error {
-	if c.WorkFile == "" {
-		g.Prepare(".", nil, c.Output)
+	return g.GenerateAll([]*Config{c})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir, WorkFile and Output of the first config are used
+func (g *Generator) GenerateAll(configs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "pfilter") }()
+	defer errutil.Catch(&err)
+
+	if len(configs) == 0 {
+		return errors.New("no config given")
+	}
+	first := configs[0]
+	if first.WorkFile == "" {
+		err = g.Prepare(first.Dir, nil, first.Output)
 	} else {
-		g.PrepareWithFile(c.WorkFile, c.Output)
+		err = g.PrepareWithFile(first.WorkFile, first.Output)
+	}
+	if err != nil {
+		return err
+	}
+	for _, c := range configs {
+		if c.TagName == "" {
+			c.TagName = "pfilter"
+		}
+		if c.Name == "" {
+			c.Name = "FilterByPaths"
+		}
+		g.Own("pfilter " + c.Type + " " + c.Name)
+		g.Run(c)
 	}
-	g.Run(c)
-	g.Output(c.Output)
-	return nil
+	return g.Output(first.Output)
 }
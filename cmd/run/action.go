@@ -0,0 +1,62 @@
+package run
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/generator/configutil"
+	"github.com/urfave/cli"
+)
+
+var Usage = "run all tasks in the manifest"
+var Description = `read the tasks from pigo.yaml, the packages are parsed with one shared parser, e.g.
+
+   version: "1"
+   tasks:
+     - dir: model
+       checker:
+         type: CreateParam
+         output: zz_checker.go
+     - dir: api
+       convert:
+         output: zz_convert.go
+         imports:
+           model: example.com/project/model
+         generates:
+           toModel:
+             source: CreateRequest
+             target: model.CreateParam
+
+the task dirs are relative to the manifest, and the paths of a task are relative to its dir
+the other generators are the plugins compiled in, or the pigo-<name> commands in PATH`
+
+var Flags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "file,f",
+		Usage: "read the manifest from `FILE`",
+		Value: "pigo.yaml",
+	},
+}
+
+func Action(c *cli.Context) error {
+	filePath := c.String("file")
+	manifest := &Manifest{}
+
+	if err := configutil.ReadConfig(filePath, manifest); err != nil {
+		return err
+	}
+
+	if manifest.Version != "" && manifest.Version != "1" {
+		return fmt.Errorf("Version not supported")
+	}
+
+	r := NewRunner()
+	r.Header = cmdutil.Header(c)
+	r.Check = c.GlobalBool("check")
+	r.Verify = c.GlobalBool("verify")
+	r.Jobs = c.GlobalInt("jobs")
+	r.Parser = cmdutil.Parser(c)
+
+	return r.Run(manifest, filepath.Dir(filePath))
+}
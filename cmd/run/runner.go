@@ -0,0 +1,345 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/lawrsp/pigo/cmd/checker"
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/cmd/convert"
+	"github.com/lawrsp/pigo/cmd/evalid"
+	"github.com/lawrsp/pigo/cmd/genrpc"
+	"github.com/lawrsp/pigo/cmd/jsonfield"
+	"github.com/lawrsp/pigo/cmd/pfilter"
+	"github.com/lawrsp/pigo/cmd/setdb"
+	"github.com/lawrsp/pigo/cmd/setter"
+	"github.com/lawrsp/pigo/cmd/template"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/parser"
+	"github.com/lawrsp/pigo/plugin"
+)
+
+// Manifest is the pigo.yaml, it lists the tasks of the project
+type Manifest struct {
+	Version string
+	Tasks   []*Task
+}
+
+// Task is one generation, the package is in Dir,
+// and exactly one of the generators should be given
+// the paths in the generator config are relative to Dir
+// the other keys are the plugins, see plugin.Find
+type Task struct {
+	Dir       string
+	Checker   *checker.Config
+	Convert   *convert.YamlConfig
+	Evalid    *evalid.Config
+	Genrpc    *genrpc.YamlConfig
+	Jsonfield *jsonfield.Config
+	Pfilter   *pfilter.Config
+	Setdb     *setdb.Config
+	Setter    *setter.Config
+	Template  *template.Config
+	Plugins   map[string]map[string]interface{} `yaml:",inline"`
+
+	plugin       plugin.Plugin
+	pluginConfig interface{}
+}
+
+func (t *Task) generators() []string {
+	names := []string{}
+	if t.Checker != nil {
+		names = append(names, "checker")
+	}
+	if t.Convert != nil {
+		names = append(names, "convert")
+	}
+	if t.Evalid != nil {
+		names = append(names, "evalid")
+	}
+	if t.Genrpc != nil {
+		names = append(names, "genrpc")
+	}
+	if t.Jsonfield != nil {
+		names = append(names, "jsonfield")
+	}
+	if t.Pfilter != nil {
+		names = append(names, "pfilter")
+	}
+	if t.Setdb != nil {
+		names = append(names, "setdb")
+	}
+	if t.Setter != nil {
+		names = append(names, "setter")
+	}
+	if t.Template != nil {
+		names = append(names, "template")
+	}
+	return append(names, generator.SortedKeys(t.Plugins)...)
+}
+
+// Runner runs the tasks with one parser, so the imported packages are parsed once
+type Runner struct {
+	Parser *parser.Parser
+	Header string
+	Check  bool
+	Verify bool
+	// Jobs is the number of tasks run at once, runtime.GOMAXPROCS if 0, see cmdutil.Schedule
+	Jobs int
+	// Outputs keeps the results in memory if not nil, nothing is written
+	Outputs *generator.Outputs
+}
+
+func NewRunner() *Runner {
+	return &Runner{Parser: parser.NewParser()}
+}
+
+// Run runs the tasks, base is the directory the task dirs are relative to,
+// the tasks of the independent packages run at once, the others in order,
+// in check mode, all tasks are checked and the out of date outputs are reported together
+func (r *Runner) Run(m *Manifest, base string) error {
+	return r.RunContext(context.Background(), m, base)
+}
+
+// RunContext is Run, which starts no more tasks when ctx is done
+func (r *Runner) RunContext(ctx context.Context, m *Manifest, base string) error {
+	outputs, err := r.Prepare(m, base)
+	if err != nil {
+		return err
+	}
+
+	jobs := make([]*cmdutil.Job, len(m.Tasks))
+	drifts := make([]error, len(m.Tasks))
+	for i, t := range m.Tasks {
+		i, t := i, t
+		jobs[i] = &cmdutil.Job{
+			Output: outputs[i],
+			Run: func() error {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				err := r.RunTask(t)
+				var drift *generator.DriftError
+				if errors.As(err, &drift) {
+					drifts[i] = err
+					return nil
+				}
+				return err
+			},
+		}
+	}
+	cmdutil.SetReads(r.Parser.ImportContext, jobs)
+
+	errs := cmdutil.Schedule(r.Jobs, jobs)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("task %d: %w", i+1, err)
+		}
+	}
+
+	messages := []string{}
+	for _, drift := range drifts {
+		if drift != nil {
+			messages = append(messages, drift.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// Prepare checks the tasks and makes their paths relative to the working directory, returns the outputs of the tasks,
+// a task is prepared once, before it is run by RunTask
+func (r *Runner) Prepare(m *Manifest, base string) ([]string, error) {
+	// the generators can share an output, except genrpc which writes the whole file
+	genrpcOutputs := map[string]int{}
+	outputs := map[string]int{}
+	result := make([]string, 0, len(m.Tasks))
+	for i, t := range m.Tasks {
+		names := t.generators()
+		if len(names) != 1 {
+			return nil, fmt.Errorf("task %d: should have exactly one generator, got %d", i+1, len(names))
+		}
+
+		dir := t.Dir
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		output, err := r.resolve(t, dir)
+		if err != nil {
+			return nil, fmt.Errorf("task %d(%s): %w", i+1, names[0], err)
+		}
+		if output == "" {
+			return nil, fmt.Errorf("task %d(%s): output should be given", i+1, names[0])
+		}
+		output = filepath.Clean(output)
+		j, ok := genrpcOutputs[output]
+		if !ok && names[0] == "genrpc" {
+			j, ok = outputs[output]
+		}
+		if ok {
+			return nil, fmt.Errorf("task %d(%s): output %s is already written by task %d", i+1, names[0], output, j+1)
+		}
+		if names[0] == "genrpc" {
+			genrpcOutputs[output] = i
+		} else if _, ok := outputs[output]; !ok {
+			outputs[output] = i
+		}
+		result = append(result, output)
+	}
+	return result, nil
+}
+
+func joinPath(dir string, name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(dir, name)
+}
+
+func joinPaths(dir string, names []string) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		result = append(result, joinPath(dir, name))
+	}
+	return result
+}
+
+// resolve makes the paths in the config relative to the working directory, returns the output
+func (r *Runner) resolve(t *Task, dir string) (string, error) {
+	switch {
+	case t.Checker != nil:
+		t.Checker.Dir = dir
+		t.Checker.Output = joinPath(dir, t.Checker.Output)
+		return t.Checker.Output, nil
+	case t.Convert != nil:
+		t.Convert.Dir = joinPath(dir, t.Convert.Dir)
+		if t.Convert.Dir == "" {
+			t.Convert.Dir = dir
+		}
+		t.Convert.Files = joinPaths(dir, t.Convert.Files)
+		t.Convert.Output = joinPath(dir, t.Convert.Output)
+		return t.Convert.Output, nil
+	case t.Evalid != nil:
+		t.Evalid.Dir = dir
+		t.Evalid.Input = joinPath(dir, t.Evalid.Input)
+		t.Evalid.Output = joinPath(dir, t.Evalid.Output)
+		return t.Evalid.Output, nil
+	case t.Genrpc != nil:
+		t.Genrpc.Dir = joinPath(dir, t.Genrpc.Dir)
+		if t.Genrpc.Dir == "" {
+			t.Genrpc.Dir = dir
+		}
+		t.Genrpc.Files = joinPaths(dir, t.Genrpc.Files)
+		t.Genrpc.Output = joinPath(dir, t.Genrpc.Output)
+		return t.Genrpc.Output, nil
+	case t.Jsonfield != nil:
+		t.Jsonfield.Dir = dir
+		t.Jsonfield.Output = joinPath(dir, t.Jsonfield.Output)
+		return t.Jsonfield.Output, nil
+	case t.Pfilter != nil:
+		t.Pfilter.Dir = dir
+		t.Pfilter.WorkFile = joinPath(dir, t.Pfilter.WorkFile)
+		t.Pfilter.Output = joinPath(dir, t.Pfilter.Output)
+		return t.Pfilter.Output, nil
+	case t.Setdb != nil:
+		t.Setdb.Dir = dir
+		t.Setdb.Output = joinPath(dir, t.Setdb.Output)
+		return t.Setdb.Output, nil
+	case t.Setter != nil:
+		t.Setter.Dir = dir
+		t.Setter.Output = joinPath(dir, t.Setter.Output)
+		return t.Setter.Output, nil
+	case t.Template != nil:
+		t.Template.Dir = dir
+		t.Template.Template = joinPath(dir, t.Template.Template)
+		t.Template.Output = joinPath(dir, t.Template.Output)
+		return t.Template.Output, nil
+	}
+	for name, values := range t.Plugins {
+		p, err := plugin.Find(name)
+		if err != nil {
+			return "", err
+		}
+		config := p.NewConfig()
+		if err := plugin.SetConfig(config, values); err != nil {
+			return "", err
+		}
+		c, err := plugin.Common(config)
+		if err != nil {
+			return "", err
+		}
+		c.Dir = dir
+		c.Output = joinPath(dir, c.Output)
+		t.plugin = p
+		t.pluginConfig = config
+		return c.Output, nil
+	}
+	return "", nil
+}
+
+func (r *Runner) setup(g *generator.Generator) {
+	g.Parser = r.Parser
+	g.Header = r.Header
+	g.Check = r.Check
+	g.Verify = r.Verify
+	g.Outputs = r.Outputs
+}
+
+// RunTask runs a task prepared by Prepare
+func (r *Runner) RunTask(t *Task) error {
+	switch {
+	case t.Checker != nil:
+		g := checker.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Checker)
+	case t.Convert != nil:
+		g := convert.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Convert)
+	case t.Evalid != nil:
+		g := evalid.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Evalid)
+	case t.Genrpc != nil:
+		g := genrpc.NewGenerator()
+		g.Parser = r.Parser
+		g.Header = r.Header
+		g.Check = r.Check
+		g.Verify = r.Verify
+		g.Outputs = r.Outputs
+		return g.Generate(t.Genrpc)
+	case t.Jsonfield != nil:
+		g := jsonfield.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Jsonfield)
+	case t.Pfilter != nil:
+		g := pfilter.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Pfilter)
+	case t.Setdb != nil:
+		g := setdb.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Setdb)
+	case t.Setter != nil:
+		g := setter.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Setter)
+	case t.Template != nil:
+		g := template.NewGenerator()
+		r.setup(&g.Generator)
+		return g.Generate(t.Template)
+	case t.plugin != nil:
+		g := &generator.Generator{}
+		r.setup(g)
+		return plugin.Run(t.plugin, g, t.pluginConfig)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/cmd/checker"
+	"github.com/lawrsp/pigo/cmd/genrpc"
+	"github.com/lawrsp/pigo/cmd/jsonfield"
+)
+
+func TestRunValidate(t *testing.T) {
+	cases := []struct {
+		tasks  []*Task
+		expect string
+	}{
+		{
+			tasks:  []*Task{{Dir: "a"}},
+			expect: "task 1: should have exactly one generator, got 0",
+		},
+		{
+			tasks: []*Task{{
+				Checker:   &checker.Config{Type: "A", Output: "a.go"},
+				Jsonfield: &jsonfield.Config{Type: "A", Output: "b.go"},
+			}},
+			expect: "task 1: should have exactly one generator, got 2",
+		},
+		{
+			tasks:  []*Task{{Checker: &checker.Config{Type: "A"}}},
+			expect: "task 1(checker): output should be given",
+		},
+		{
+			tasks: []*Task{
+				{Dir: "a", Checker: &checker.Config{Type: "A", Output: "zz.go"}},
+				{Jsonfield: &jsonfield.Config{Type: "A", Output: "a/zz.go"}},
+				{Genrpc: &genrpc.YamlConfig{Output: "a/zz.go"}},
+			},
+			expect: "task 3(genrpc): output base/a/zz.go is already written by task 1",
+		},
+		{
+			tasks: []*Task{
+				{Genrpc: &genrpc.YamlConfig{Output: "zz.go"}},
+				{Checker: &checker.Config{Type: "A", Output: "zz.go"}},
+			},
+			expect: "task 2(checker): output base/zz.go is already written by task 1",
+		},
+	}
+
+	for i, c := range cases {
+		err := NewRunner().Run(&Manifest{Tasks: c.tasks}, "base")
+		if err == nil || !strings.Contains(err.Error(), c.expect) {
+			t.Errorf("case %d: expect %q, got %v", i, c.expect, err)
+		}
+	}
+}
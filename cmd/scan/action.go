@@ -0,0 +1,44 @@
+package scan
+
+import (
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/urfave/cli"
+)
+
+var Usage = "run the generators of the //pigo: directives in the packages"
+var Description = `find the directives in the doc comments of the type declarations, e.g.
+
+   //pigo:checker name=Validate tag=checker
+   //pigo:setter target=B withmap checkdiff
+   type A struct {
+      ...
+   }
+
+the arguments are the flags of the generator without "--", and the type is the declared one,
+the outputs are grouped per package, into zz_generated.go unless output= is given,
+import= takes a comma separated list of "name:path" or "path"
+the other generators are the plugins compiled in, or the pigo-<name> commands in PATH
+
+the packages are given as directories, "./..." means the current directory and all its sub directories`
+
+var Flags = []cli.Flag{}
+
+func Action(c *cli.Context) error {
+	patterns := []string(c.Args())
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	dirs, err := Dirs(patterns)
+	if err != nil {
+		return err
+	}
+
+	s := NewScanner()
+	s.Header = cmdutil.Header(c)
+	s.Check = c.GlobalBool("check")
+	s.Verify = c.GlobalBool("verify")
+	s.Jobs = c.GlobalInt("jobs")
+	s.Parser = cmdutil.Parser(c)
+	return s.Run(dirs)
+}
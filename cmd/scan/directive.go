@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+const directivePrefix = "//pigo:"
+
+// Directive is a `//pigo:<generator> key=value flag ...` comment on a type declaration
+type Directive struct {
+	Generator string
+	Type      string
+	// Args holds the key=value pairs, a flag without value is "true"
+	Args     map[string]string
+	Position token.Position
+}
+
+func (d *Directive) String() string {
+	return fmt.Sprintf("%s: //pigo:%s on %s", d.Position, d.Generator, d.Type)
+}
+
+// ParseDirective parses the text of one comment line, ok is false if it is not a directive
+func ParseDirective(text string) (generator string, args map[string]string, ok bool) {
+	if !strings.HasPrefix(text, directivePrefix) {
+		return "", nil, false
+	}
+
+	fields := strings.Fields(text[len(directivePrefix):])
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+
+	args = map[string]string{}
+	for _, field := range fields[1:] {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 1 {
+			args[kv[0]] = "true"
+		} else {
+			args[kv[0]] = kv[1]
+		}
+	}
+
+	return fields[0], args, true
+}
+
+func parseDirectives(p *parser.Parser, typeName string, groups ...*ast.CommentGroup) []*Directive {
+	directives := []*Directive{}
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, comment := range group.List {
+			generator, args, ok := ParseDirective(comment.Text)
+			if !ok {
+				continue
+			}
+			directives = append(directives, &Directive{
+				Generator: generator,
+				Type:      typeName,
+				Args:      args,
+				Position:  p.Position(comment.Pos()),
+			})
+		}
+	}
+	return directives
+}
+
+// FindDirectives returns the directives of the types in the package, in source order
+// the directive can be in the doc of the type declaration, or of the type spec in a group
+func FindDirectives(p *parser.Parser, pkg *parser.Package) []*Directive {
+	directives := []*Directive{}
+	parser.WalkPackage(pkg, parser.NewGenDeclWalker(token.TYPE, func(decl *ast.GenDecl) bool {
+		for _, spec := range decl.Specs {
+			ts := spec.(*ast.TypeSpec)
+			groups := []*ast.CommentGroup{}
+			if len(decl.Specs) == 1 {
+				groups = append(groups, decl.Doc)
+			}
+			groups = append(groups, ts.Doc)
+			directives = append(directives, parseDirectives(p, ts.Name.Name, groups...)...)
+		}
+		return true
+	}))
+	return directives
+}
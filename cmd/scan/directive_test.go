@@ -0,0 +1,65 @@
+package scan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+func TestParseDirective(t *testing.T) {
+	name, args, ok := ParseDirective("//pigo:setter target=B withmap checkdiff")
+	if !ok || name != "setter" {
+		t.Fatalf("expect setter directive, got %s %v", name, ok)
+	}
+	expect := map[string]string{"target": "B", "withmap": "true", "checkdiff": "true"}
+	if !reflect.DeepEqual(args, expect) {
+		t.Errorf("expect %v, got %v", expect, args)
+	}
+
+	for _, text := range []string{"// pigo:setter", "//go:generate pigo", "//pigo:"} {
+		if _, _, ok := ParseDirective(text); ok {
+			t.Errorf("%q should not be a directive", text)
+		}
+	}
+}
+
+func TestFindDirectives(t *testing.T) {
+	src := `package a
+
+// A is a
+//pigo:checker name=Check
+type A struct{}
+
+type (
+	//pigo:jsonfield
+	B struct{}
+	C struct{}
+)
+
+//pigo:evalid
+const D = 1
+`
+	p := parser.NewParser()
+	file, err := p.ParseFileContent("a.go", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := parser.NewPackage(p, "a", ".", "", []*parser.File{file})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directives := FindDirectives(p, pkg)
+	got := []string{}
+	for _, d := range directives {
+		got = append(got, d.Generator+":"+d.Type)
+	}
+	expect := []string{"checker:A", "jsonfield:B"}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("expect %v, got %v", expect, got)
+	}
+	if directives[0].Args["name"] != "Check" || directives[0].Position.Line != 4 {
+		t.Errorf("unexpected directive %v %v", directives[0], directives[0].Args)
+	}
+}
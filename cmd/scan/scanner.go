@@ -0,0 +1,464 @@
+package scan
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lawrsp/pigo/cmd/checker"
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/cmd/evalid"
+	"github.com/lawrsp/pigo/cmd/jsonfield"
+	"github.com/lawrsp/pigo/cmd/pfilter"
+	"github.com/lawrsp/pigo/cmd/setdb"
+	"github.com/lawrsp/pigo/cmd/setter"
+	"github.com/lawrsp/pigo/cmd/template"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/parser"
+	"github.com/lawrsp/pigo/plugin"
+)
+
+// Generators are the generators can be used in directives, in the order they run,
+// the plugins run after them, by name, see plugin.Find
+var Generators = []string{"checker", "setter", "jsonfield", "setdb", "pfilter", "evalid", "template"}
+
+// DefaultOutput is the output of the generators in a package, if not given by output=
+// the generators share it, see generator.OwnerPrefix
+const DefaultOutput = "zz_generated.go"
+
+// Group is the directives generated into one output
+type Group struct {
+	Dir        string
+	Generator  string
+	Output     string
+	Directives []*Directive
+
+	plugin plugin.Plugin
+}
+
+// Scanner finds the directives in the packages and runs the generators
+type Scanner struct {
+	Parser *parser.Parser
+	Header string
+	Check  bool
+	Verify bool
+	// Jobs is the number of groups run at once, runtime.GOMAXPROCS if 0, see cmdutil.Schedule
+	Jobs int
+}
+
+func NewScanner() *Scanner {
+	return &Scanner{Parser: parser.NewParser()}
+}
+
+// Dirs returns the package directories matched by the patterns,
+// "dir/..." matches dir and all the directories in it,
+// testdata, vendor and the directories start with "." or "_" are skipped
+func Dirs(patterns []string) ([]string, error) {
+	dirs := []string{}
+	seen := map[string]bool{}
+	add := func(dir string) {
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	for _, pattern := range patterns {
+		if pattern != "..." && !strings.HasSuffix(pattern, "/...") {
+			add(pattern)
+			continue
+		}
+
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			name := info.Name()
+			if path != root && (name == "testdata" || name == "vendor" ||
+				strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+
+			if _, err := build.ImportDir(path, 0); err != nil {
+				var noGo *build.NoGoError
+				if errors.As(err, &noGo) {
+					return nil
+				}
+			}
+			add(path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// Groups returns the directives in the package directory, grouped by generator and output
+func (s *Scanner) Groups(dir string) ([]*Group, error) {
+	pkg, err := s.Parser.ParsePackageDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	directives := FindDirectives(s.Parser, pkg)
+	names := append([]string{}, Generators...)
+	plugins := map[string]plugin.Plugin{}
+	for _, d := range directives {
+		if _, ok := newConfig[d.Generator]; ok {
+			continue
+		}
+		if _, ok := plugins[d.Generator]; ok {
+			continue
+		}
+		p, err := plugin.Find(d.Generator)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Position, err)
+		}
+		plugins[d.Generator] = p
+	}
+	names = append(names, generator.SortedKeys(plugins)...)
+
+	groups := []*Group{}
+	index := map[string]*Group{}
+	for _, name := range names {
+		for _, d := range directives {
+			if d.Generator != name {
+				continue
+			}
+			output := d.Args["output"]
+			if output == "" {
+				output = DefaultOutput
+			}
+			output = filepath.Join(dir, output)
+
+			key := name + ":" + output
+			group, ok := index[key]
+			if !ok {
+				group = &Group{Dir: dir, Generator: name, Output: output, plugin: plugins[name]}
+				index[key] = group
+				groups = append(groups, group)
+			}
+			group.Directives = append(group.Directives, d)
+		}
+	}
+
+	return groups, nil
+}
+
+// Run finds the directives in the package directories and runs the generators,
+// the groups of the independent packages run at once, the others in order,
+// in check mode, all groups are checked and the out of date outputs are reported together
+func (s *Scanner) Run(dirs []string) error {
+	groups := []*Group{}
+	for _, dir := range dirs {
+		found, err := s.Groups(dir)
+		if err != nil {
+			return err
+		}
+		groups = append(groups, found...)
+	}
+
+	jobs := make([]*cmdutil.Job, len(groups))
+	drifts := make([]error, len(groups))
+	for i, group := range groups {
+		i, group := i, group
+		jobs[i] = &cmdutil.Job{
+			Output: group.Output,
+			Run: func() error {
+				log.Printf("scan %s: %s -> %s", group.Dir, group.Generator, group.Output)
+				err := s.RunGroup(group)
+				var drift *generator.DriftError
+				if errors.As(err, &drift) {
+					drifts[i] = err
+					return nil
+				}
+				return err
+			},
+		}
+	}
+	cmdutil.SetReads(s.Parser.ImportContext, jobs)
+
+	for _, err := range cmdutil.Schedule(s.Jobs, jobs) {
+		if err != nil {
+			return err
+		}
+	}
+
+	messages := []string{}
+	for _, drift := range drifts {
+		if drift != nil {
+			messages = append(messages, drift.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+func (s *Scanner) setup(g *generator.Generator) {
+	g.Parser = s.Parser
+	g.Header = s.Header
+	g.Check = s.Check
+	g.Verify = s.Verify
+}
+
+// RunGroup runs the generator of the group
+func (s *Scanner) RunGroup(group *Group) error {
+	if group.plugin != nil {
+		return s.runPlugin(group)
+	}
+
+	configs := []interface{}{}
+	for _, d := range group.Directives {
+		a := &args{d: d, used: map[string]bool{"output": true}}
+		config, err := newConfig[group.Generator](a, group.Dir, group.Output)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.Position, err)
+		}
+		if err := a.unused(); err != nil {
+			return fmt.Errorf("%s: %w", d.Position, err)
+		}
+		configs = append(configs, config)
+	}
+
+	switch group.Generator {
+	case "checker":
+		g := checker.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*checker.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*checker.Config))
+		}
+		return g.GenerateAll(cs)
+	case "setter":
+		g := setter.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*setter.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*setter.Config))
+		}
+		return g.GenerateAll(cs)
+	case "jsonfield":
+		g := jsonfield.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*jsonfield.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*jsonfield.Config))
+		}
+		return g.GenerateAll(cs)
+	case "setdb":
+		g := setdb.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*setdb.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*setdb.Config))
+		}
+		return g.GenerateAll(cs)
+	case "pfilter":
+		g := pfilter.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*pfilter.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*pfilter.Config))
+		}
+		return g.GenerateAll(cs)
+	case "evalid":
+		g := evalid.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*evalid.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*evalid.Config))
+		}
+		return g.GenerateAll(cs)
+	case "template":
+		g := template.NewGenerator()
+		s.setup(&g.Generator)
+		cs := []*template.Config{}
+		for _, c := range configs {
+			cs = append(cs, c.(*template.Config))
+		}
+		return g.GenerateAll(cs)
+	}
+	return nil
+}
+
+// runPlugin runs the plugin of the group, the directive arguments are set by plugin.SetConfig
+func (s *Scanner) runPlugin(group *Group) error {
+	configs := []interface{}{}
+	for _, d := range group.Directives {
+		values := map[string]interface{}{"type": d.Type}
+		for key, value := range d.Args {
+			if key != "output" {
+				values[key] = value
+			}
+		}
+		config := group.plugin.NewConfig()
+		if err := plugin.SetConfig(config, values); err != nil {
+			return fmt.Errorf("%s: %w of //pigo:%s", d.Position, err, d.Generator)
+		}
+		c, err := plugin.Common(config)
+		if err != nil {
+			return err
+		}
+		c.Dir = group.Dir
+		c.Output = group.Output
+		configs = append(configs, config)
+	}
+
+	g := &generator.Generator{}
+	s.setup(g)
+	return plugin.RunAll(group.plugin, g, configs)
+}
+
+// args reads the directive arguments, and reports the unknown ones
+type args struct {
+	d    *Directive
+	used map[string]bool
+}
+
+func (a *args) String(key string) string {
+	a.used[key] = true
+	return a.d.Args[key]
+}
+
+func (a *args) Bool(key string) bool {
+	a.used[key] = true
+	return a.d.Args[key] == "true"
+}
+
+// Imports reads import=name:path,path2
+func (a *args) Imports() map[string]string {
+	v := a.String("import")
+	if v == "" {
+		return map[string]string{}
+	}
+	return cmdutil.Imports(strings.Split(v, ","))
+}
+
+func (a *args) unused() error {
+	keys := []string{}
+	for key := range a.d.Args {
+		if !a.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		return fmt.Errorf("unknown argument %s of //pigo:%s", strings.Join(keys, ","), a.d.Generator)
+	}
+	return nil
+}
+
+// newConfig creates the config of the generators from the directive
+var newConfig = map[string]func(a *args, dir string, output string) (interface{}, error){
+	"checker": func(a *args, dir string, output string) (interface{}, error) {
+		return &checker.Config{
+			Dir:      dir,
+			Type:     a.d.Type,
+			Name:     a.String("name"),
+			TagName:  a.String("tag"),
+			Imports:  a.Imports(),
+			Checker:  a.String("checker"),
+			Context:  a.Bool("context"),
+			FailFast: a.Bool("failfast"),
+			Output:   output,
+		}, nil
+	},
+	"setter": func(a *args, dir string, output string) (interface{}, error) {
+		c := &setter.Config{
+			Dir:        dir,
+			Type:       a.d.Type,
+			Receiver:   a.String("receiver"),
+			Target:     a.String("target"),
+			Name:       a.String("name"),
+			Withmap:    a.Bool("withmap"),
+			WithOldMap: a.Bool("withold"),
+			CheckDiff:  a.Bool("checkdiff"),
+			MapTag:     a.String("maptag"),
+			TagName:    a.String("tag"),
+			Imports:    a.Imports(),
+			Output:     output,
+		}
+		if c.Target != "" && c.Receiver != "" {
+			return nil, errors.New("receiver and target cannot be used together")
+		}
+		if c.Target == "" && c.Receiver == "" {
+			return nil, errors.New("please specify recevier or target")
+		}
+		return c, nil
+	},
+	"jsonfield": func(a *args, dir string, output string) (interface{}, error) {
+		return &jsonfield.Config{
+			Dir:     dir,
+			Type:    a.d.Type,
+			Name:    a.String("name"),
+			TagName: a.String("tag"),
+			Output:  output,
+		}, nil
+	},
+	"setdb": func(a *args, dir string, output string) (interface{}, error) {
+		return &setdb.Config{
+			Dir:     dir,
+			Type:    a.d.Type,
+			Name:    a.String("name"),
+			TagName: a.String("tag"),
+			DBType:  a.String("db"),
+			Imports: a.Imports(),
+			Output:  output,
+		}, nil
+	},
+	"pfilter": func(a *args, dir string, output string) (interface{}, error) {
+		return &pfilter.Config{
+			Dir:     dir,
+			Type:    a.d.Type,
+			Name:    a.String("name"),
+			TagName: a.String("tag"),
+			Output:  output,
+		}, nil
+	},
+	"evalid": func(a *args, dir string, output string) (interface{}, error) {
+		c := &evalid.Config{
+			Dir:    dir,
+			Type:   a.d.Type,
+			Const:  a.String("const"),
+			Name:   a.String("name"),
+			Output: output,
+		}
+		if c.Const == "" {
+			c.Const = c.Type
+		}
+		return c, nil
+	},
+	"template": func(a *args, dir string, output string) (interface{}, error) {
+		c := &template.Config{
+			Dir:      dir,
+			Type:     a.d.Type,
+			Template: a.String("template"),
+			Name:     a.String("name"),
+			TagName:  a.String("tag"),
+			Imports:  a.Imports(),
+			Output:   output,
+		}
+		if c.Template == "" {
+			return nil, errors.New("template should be given")
+		}
+		c.Template = filepath.Join(dir, c.Template)
+		return c, nil
+	},
+}
@@ -2,8 +2,8 @@ package setdb
 
 import (
 	"fmt"
-	"strings"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -51,19 +51,7 @@ func Action(c *cli.Context) error {
 	output := c.String("output")
 	input := c.Args().Get(0)
 
-	imports := c.StringSlice("import")
-	configImports := map[string]string{}
-	for _, impt := range imports {
-		kv := strings.Split(impt, ":")
-		if len(kv) == 1 {
-			path := kv[0]
-			names := strings.Split(path, "/")
-			name := names[len(names)-1]
-			configImports[name] = path
-		} else {
-			configImports[kv[0]] = kv[1]
-		}
-	}
+	configImports := cmdutil.Imports(c.StringSlice("import"))
 
 	config := &Config{
 		Type:    t,
@@ -76,6 +64,10 @@ func Action(c *cli.Context) error {
 	}
 
 	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 
 	return g.Generate(config)
 }
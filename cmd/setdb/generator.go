@@ -1,17 +1,20 @@
 package setdb
 
 import (
+	"errors"
 	"log"
 	"reflect"
 	"strings"
 
 	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 	"github.com/lawrsp/stringstyles"
 )
 
 type Config struct {
+	Dir     string
 	Name    string
 	TagName string
 	Output  string
@@ -184,6 +187,9 @@ func (g *Generator) PrepareTask(conf *Config) {
 	cnds := []*Condition{}
 
 	t := g.File.ReduceTypeSrc(conf.Type)
+	if t == nil {
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(conf.Type))
+	}
 	fieldList := builder.NewFieldList(conf.TagName)
 	_ = parser.InspectUnderlyingStruct(t, fieldList.SpreadInspector)
 
@@ -216,15 +222,17 @@ func (g *Generator) Run(conf *Config) {
 	file := builder.NewFile(nil, g.File)
 
 	if conf.Imports != nil && len(conf.Imports) > 0 {
-		for name, path := range conf.Imports {
-			file.AddImport(name, path)
+		for _, name := range generator.SortedKeys(conf.Imports) {
+			file.AddImport(name, conf.Imports[name])
 		}
 	}
 
 	bd := builder.NewFuncBuffer(file, conf.Name)
 
 	bd.Printf("\n")
-	bd.Printf("func (p *%s) %s(db *%[3]s) *%[3]s {\n", conf.Type, conf.Name, conf.DBType)
+	// the receiver of a generic type is like Page[T]
+	recvExpr := parser.ExprToString(parser.TypeExprInFile(g.File.ReduceTypeSrc(conf.Type), g.File))
+	bd.Printf("func (p *%s) %s(db *%[3]s) *%[3]s {\n", recvExpr, conf.Name, conf.DBType)
 	bd.Printf("if p == nil {\n")
 	bd.Printf("  return db\n")
 	bd.Printf("}\n")
@@ -251,11 +259,27 @@ func (g *Generator) Run(conf *Config) {
 }
 
 func (g *Generator) Generate(conf *Config) error {
+	return g.GenerateAll([]*Config{conf})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(confs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "setdb") }()
+	defer errutil.Catch(&err)
 
-	g.Prepare(".", nil, conf.Output)
-	g.PrepareTask(conf)
-	g.Run(conf)
+	if len(confs) == 0 {
+		return errors.New("no config given")
+	}
+	first := confs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, conf := range confs {
+		g.PrepareTask(conf)
+		g.Own("setdb " + conf.Type + " " + conf.Name)
+		g.Run(conf)
+	}
 
-	g.Output(conf.Output)
-	return nil
+	return g.Output(first.Output)
 }
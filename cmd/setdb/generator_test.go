@@ -0,0 +1,13 @@
+package setdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAllEmpty(t *testing.T) {
+	g := NewGenerator()
+	if err := g.GenerateAll(nil); err == nil || !strings.Contains(err.Error(), "no config given") {
+		t.Errorf("expect no config error, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/lawrsp/pigo/cmd/cmdutil"
 	"github.com/urfave/cli"
 )
 
@@ -95,6 +96,7 @@ func Action(c *cli.Context) error {
 		Output:     output,
 		Target:     target,
 		CheckDiff:  checkDiff,
+		TagName:    c.String("tag"),
 	}
 
 	// assigns:
@@ -114,24 +116,15 @@ func Action(c *cli.Context) error {
 	config.Assigns = configAssigns
 
 	// imports:
-	imports := c.StringSlice("import")
-	configImports := map[string]string{}
-	for _, impt := range imports {
-		kv := strings.Split(impt, ":")
-		if len(kv) == 1 {
-			path := kv[0]
-			names := strings.Split(path, "/")
-			name := names[len(names)-1]
-			configImports[name] = path
-		} else {
-			configImports[kv[0]] = kv[1]
-		}
-	}
+	configImports := cmdutil.Imports(c.StringSlice("import"))
 	config.Imports = configImports
 
 	g := NewGenerator()
-	g.TagName = c.String("tag")
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
 
-	log.Printf("start setter %s", g.TagName)
+	log.Printf("start setter %s", config.TagName)
 	return g.Generate(config)
 }
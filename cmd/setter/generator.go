@@ -1,6 +1,7 @@
 package setter
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"log"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/lawrsp/pigo/generator/builder"
 	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 	"github.com/lawrsp/pigo/generator/tagutil"
 )
@@ -28,6 +30,7 @@ type AssignConfig struct {
 }
 
 type Config struct {
+	Dir        string
 	Type       string
 	Receiver   string
 	Target     string
@@ -37,6 +40,7 @@ type Config struct {
 	CheckDiff  bool
 	Output     string
 	MapTag     string
+	TagName    string
 	Imports    map[string]string
 	Assigns    []*AssignConfig
 }
@@ -70,15 +74,15 @@ func (g *Generator) PrepareAssigns(configAssigns []*AssignConfig) {
 		cp := &CustomAssign{}
 		cp.Source = g.ReduceTypeSrc(v.Source)
 		if cp.Source == nil {
-			log.Fatalf("type %s not reduced", v.Source)
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Source))
 		}
 		cp.Target = g.ReduceTypeSrc(v.Target)
 		if cp.Target == nil {
-			log.Fatalf("type %s not reduced", v.Target)
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Target))
 		}
 		cp.Assign = g.ReduceTypeSrc(v.Assign)
 		if cp.Assign == nil {
-			log.Fatalf("type %s not reduced", v.Assign)
+			errutil.Throw(errutil.New("type not reduced").WithExpr(v.Assign))
 		}
 		cp.Check = v.Check
 		assigns = append(assigns, cp)
@@ -88,12 +92,20 @@ func (g *Generator) PrepareAssigns(configAssigns []*AssignConfig) {
 }
 
 func (g *Generator) PrepareTask(config *Config) {
-	g.Type = parser.TypeWithPointer(g.File.ReduceTypeSrc(config.Type))
+	t := g.File.ReduceTypeSrc(config.Type)
+	if t == nil {
+		errutil.Throw(errutil.New("cannot reduce type").WithExpr(config.Type))
+	}
+	g.Type = parser.TypeWithPointer(t)
 	funcName := config.Name
 	var fnt *parser.FuncType
 
 	if config.Receiver != "" {
-		g.Receiver = parser.TypeWithPointer(g.File.ReduceTypeSrc(config.Receiver))
+		recv := g.File.ReduceTypeSrc(config.Receiver)
+		if recv == nil {
+			errutil.Throw(errutil.New("cannot reduce type").WithExpr(config.Receiver))
+		}
+		g.Receiver = parser.TypeWithPointer(recv)
 
 		if funcName == "" {
 			funcName = fmt.Sprintf("Set%s", config.Type)
@@ -111,7 +123,7 @@ func (g *Generator) PrepareTask(config *Config) {
 		log.Printf("target: %s", config.Target)
 		tRecv := g.File.ReduceTypeSrc(config.Target)
 		if tRecv == nil {
-			log.Fatalf("cannot reduce type %s", config.Target)
+			errutil.Throw(errutil.New("cannot reduce type").WithExpr(config.Target))
 		}
 		g.Receiver = parser.TypeWithPointer(tRecv)
 		if funcName == "" {
@@ -198,6 +210,7 @@ func (g *Generator) AssignerFunc(field *builder.Field) func(builder.Builder, *bu
 }
 
 func (g *Generator) Run() {
+	defer errutil.CatchAt(parser.TypePosition(g.Type))
 
 	outer := builder.NewFile(nil, g.File)
 	var fb *builder.FuncBuilder
@@ -263,13 +276,36 @@ func (g *Generator) Run() {
 }
 
 func (g *Generator) Generate(config *Config) error {
+	return g.GenerateAll([]*Config{config})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(configs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "setter") }()
+	defer errutil.Catch(&err)
 
-	g.Prepare(".", nil, config.Output)
-	g.PrepareImports(config.Imports)
-	g.PrepareAssigns(config.Assigns)
-	g.PrepareTask(config)
-	g.Run()
-	g.Output(config.Output)
+	if len(configs) == 0 {
+		return errors.New("no config given")
+	}
+	first := configs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, config := range configs {
+		g.TagName = config.TagName
+		if g.TagName == "" {
+			g.TagName = "setter"
+		}
+
+		if err := g.PrepareImports(config.Imports); err != nil {
+			return err
+		}
+		g.PrepareAssigns(config.Assigns)
+		g.PrepareTask(config)
+		g.Own("setter " + config.Type + " " + g.FuncType.Name())
+		g.Run()
+	}
 
-	return nil
+	return g.Output(first.Output)
 }
@@ -0,0 +1,48 @@
+package setter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// the runs for the same type with different names share the output, a rerun keeps the others
+func TestGenerateSameType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := "package a\n\ntype A struct {\n\tName string\n}\n\ntype B struct {\n\tName string\n}\n\ntype C struct {\n\tName string\n}\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "zz_setter.go")
+	configs := []*Config{
+		{Dir: dir, Type: "A", Target: "B", Name: "SetB", Output: output},
+		{Dir: dir, Type: "A", Target: "C", Name: "SetC", Output: output},
+		{Dir: dir, Type: "A", Target: "B", Name: "SetB", Output: output},
+	}
+	for _, config := range configs {
+		if err := NewGenerator().Generate(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"//pigo:owner setter A SetB\nfunc (t *A) SetB(target *B) {",
+		"//pigo:owner setter A SetC\nfunc (t *A) SetC(target *C) {",
+	} {
+		if strings.Count(string(result), expect) != 1 {
+			t.Errorf("expect %q once in:\n%s", expect, result)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package setter
+
+import (
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	testutil.Run(t, "testdata", func(c *testutil.Case) error {
+		config := &Config{}
+		if err := c.ReadConfig(config); err != nil {
+			return err
+		}
+		config.Dir = c.Dir
+		config.Output = c.Path(config.Output)
+
+		g := NewGenerator()
+		g.Parser = c.Parser
+		g.Outputs = c.Outputs
+		return g.Generate(config)
+	})
+}
@@ -0,0 +1,11 @@
+package model
+
+type UpdateParam struct {
+	Name  string
+	Count int `setter:"Total"`
+}
+
+type User struct {
+	Name  string
+	Total int
+}
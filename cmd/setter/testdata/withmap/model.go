@@ -0,0 +1,13 @@
+package model
+
+type A struct {
+	Foo      int `setter:"Bar"`
+	SameName string
+	Skip     string `setter:"-"`
+}
+
+type B struct {
+	Bar      int
+	SameName string
+	Skip     string
+}
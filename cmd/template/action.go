@@ -0,0 +1,77 @@
+package template
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/urfave/cli"
+)
+
+var Usage = "generate code from a text/template of the type"
+var Description = `execute the template with the type model, the result is the declarations to add to the output,
+without the package clause, the standard imports are added when used, the others are given by --import, e.g.
+
+   {{range .Type.Fields}}const {{$.Type.Name}}{{.Name}} = {{quote (tag . "json")}}
+   {{end}}
+
+the data is the Data struct, .Type.Fields are the fields with the embedded structs spread,
+and .Type.Methods are the methods declared in the package of the type
+the functions are snake, screaming, camel, pascal, kebab, lower, upper, join, quote,
+tag (the name part of a tag key of a field) and typeExpr (the expression of a parser.Type in the output)`
+
+var Flags = []cli.Flag{
+	//template -t User --template stringer.tmpl -o zz_stringer.go
+	cli.StringFlag{
+		Name:  "type,t",
+		Usage: "the `TYPE` to generate",
+	},
+	cli.StringFlag{
+		Name:  "template",
+		Usage: "the text/template `FILE`",
+	},
+	cli.StringFlag{
+		Name:  "name,n",
+		Usage: "the `NAME` passed to the template as .Name",
+	},
+	cli.StringFlag{
+		Name:  "tag,g",
+		Usage: "tag name to define the key of the fields, the fields tagged \"-\" are skipped",
+	},
+	cli.StringSliceFlag{
+		Name:  "import,i",
+		Usage: "the requried imports",
+	},
+	cli.StringFlag{
+		Name:  "output,o",
+		Usage: "the `FILE` to output",
+	},
+}
+
+func Action(c *cli.Context) error {
+	t := c.String("type")
+	if t == "" {
+		return fmt.Errorf("type should be given")
+	}
+	tmpl := c.String("template")
+	if tmpl == "" {
+		return fmt.Errorf("template should be given")
+	}
+
+	config := &Config{
+		Type:     t,
+		Template: tmpl,
+		Name:     c.String("name"),
+		TagName:  c.String("tag"),
+		Imports:  cmdutil.Imports(c.StringSlice("import")),
+		Output:   c.String("output"),
+	}
+
+	g := NewGenerator()
+	g.Header = cmdutil.Header(c)
+	g.Check = c.GlobalBool("check")
+	g.Verify = c.GlobalBool("verify")
+	g.Parser = cmdutil.Parser(c)
+	log.Printf("start template:")
+	return g.Generate(config)
+}
@@ -0,0 +1,341 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	goparser "go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
+	"github.com/lawrsp/pigo/generator/parser"
+	"github.com/lawrsp/stringstyles"
+)
+
+type Config struct {
+	Dir      string
+	Type     string
+	Template string
+	Name     string
+	TagName  string
+	Imports  map[string]string
+	Output   string
+}
+
+// Data is the data of the template
+type Data struct {
+	// Package is the package name of the output
+	Package string
+	// Name is the --name, e.g. the function to generate
+	Name string
+	Type *Type
+}
+
+// Type is the --type as in the output file
+type Type struct {
+	// Name is the type expression, e.g. "User", "model.User" or "Page[T]"
+	Name string
+	// Receiver is the pointer receiver of the methods, e.g. "*Page[T]"
+	Receiver string
+	// Underlying is "struct" for the structs, or the expression of the underlying type
+	Underlying string
+	// Fields are the fields of the struct, the embedded structs are spread, see builder.FieldList
+	Fields []*Field
+	// Methods are the methods declared in the package of the type, the ones in the output are not included,
+	// so a rerun sees the methods of the first run
+	Methods []*Method
+	Type    parser.Type
+}
+
+type Field struct {
+	// Name is the field name
+	Name string
+	// Key is the name in the --tag, or the field name
+	Key string
+	// Type is the type expression
+	Type string
+	Tag  reflect.StructTag
+	// Stars is the pointer depth of the type, e.g. 1 of *User
+	Stars int
+	// Embedded is an embedded field which is not a struct, e.g. an interface
+	Embedded bool
+	Field    *parser.Field
+}
+
+type Method struct {
+	Name string
+	// Pointer is true if the receiver is a pointer
+	Pointer bool
+	// Signature is the params and results as declared, e.g. "(ctx context.Context) error"
+	Signature string
+}
+
+type Generator struct {
+	generator.Generator
+	TagName string
+
+	// the imports added by importDeclared
+	declared []*ast.ImportSpec
+}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+// Funcs are the functions can be used in the templates
+func (g *Generator) Funcs() gotemplate.FuncMap {
+	return gotemplate.FuncMap{
+		"snake":     stringstyles.SnakeCase,
+		"screaming": stringstyles.ScreamingSnakeCase,
+		"camel":     stringstyles.CamelCase,
+		"pascal":    stringstyles.PascalCase,
+		"kebab":     stringstyles.KebabCase,
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"join":      strings.Join,
+		"quote":     strconv.Quote,
+		// tag returns the name part of the tag key, e.g. "name" of `json:"name,omitempty"`
+		"tag": func(f *Field, key string) string {
+			return strings.Split(f.Tag.Get(key), ",")[0]
+		},
+		// typeExpr returns the expression of a parser.Type in the output file
+		"typeExpr": g.exprOf,
+	}
+}
+
+func (g *Generator) exprOf(t parser.Type) string {
+	return parser.ExprToString(parser.TypeExprInFile(t, g.File))
+}
+
+// NewType returns the model of the type expression src
+func (g *Generator) NewType(src string) *Type {
+	t := g.ReduceTypeSrc(src)
+	if t == nil {
+		errutil.Throw(errutil.New("type not reduced").WithExpr(src))
+	}
+
+	g.importDeclared(t)
+	m := &Type{
+		Name:     g.exprOf(t),
+		Receiver: g.exprOf(parser.TypeWithPointer(t)),
+		Type:     t,
+	}
+	if _, ok := t.Underlying().(*parser.StructType); ok {
+		m.Underlying = "struct"
+	} else {
+		m.Underlying = g.exprOf(t.Underlying())
+	}
+
+	list := builder.NewFieldList(g.TagName)
+	parser.InspectUnderlyingStruct(t, list.SpreadInspector)
+	for _, fd := range list.Fields {
+		m.Fields = append(m.Fields, &Field{
+			Name:     fd.Field.Name(),
+			Key:      fd.Name,
+			Type:     g.exprOf(fd.Field.Type),
+			Tag:      reflect.StructTag(fd.Field.Tag),
+			Stars:    parser.GetTypeStars(fd.Field.Type),
+			Embedded: fd.Field.IsAnonymous(),
+			Field:    fd.Field,
+		})
+	}
+
+	output := map[ast.Decl]bool{}
+	for _, decl := range g.File.File.Decls {
+		output[decl] = true
+	}
+	for _, fd := range parser.Methods(t) {
+		if output[fd] {
+			continue
+		}
+		_, pointer := fd.Recv.List[0].Type.(*ast.StarExpr)
+		var signature bytes.Buffer
+		if err := format.Node(&signature, g.Parser.FileSet, fd.Type); err != nil {
+			errutil.Throw(err)
+		}
+		m.Methods = append(m.Methods, &Method{
+			Name:      fd.Name.Name,
+			Pointer:   pointer,
+			Signature: strings.TrimPrefix(signature.String(), "func"),
+		})
+	}
+	return m
+}
+
+// importDeclared adds the imports of the file declaring t to the output, so its field types can be written,
+// the ones not used by the template are removed, see removeUnused
+func (g *Generator) importDeclared(t parser.Type) {
+	file := t.File()
+	if file == nil || file == g.File {
+		return
+	}
+	fb := builder.NewFile(nil, g.File)
+	for _, spec := range file.File.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			errutil.Throw(err)
+		}
+		name := importName(spec)
+		if name == "_" || name == "." {
+			continue
+		}
+		if _, ok := g.File.FindImportPath(name); ok {
+			continue
+		}
+		fb.AddImport(name, path)
+		g.declared = append(g.declared, g.File.File.Imports[len(g.File.File.Imports)-1])
+	}
+}
+
+// importName returns the name of the import, the last element of the path if it is not named
+func importName(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+	path, _ := strconv.Unquote(spec.Path.Value)
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// removeUnused removes the imports added by importDeclared which code does not use,
+// so the imports of the output, and of a rerun, are only the ones of the declarations
+func (g *Generator) removeUnused(code []byte) {
+	used := usedPackages(code)
+	unused := map[*ast.ImportSpec]bool{}
+	for _, spec := range g.declared {
+		if !used[importName(spec)] {
+			unused[spec] = true
+		}
+	}
+	g.declared = nil
+
+	file := g.File.File
+	imports := file.Imports[:0]
+	for _, spec := range file.Imports {
+		if !unused[spec] {
+			imports = append(imports, spec)
+		}
+	}
+	file.Imports = imports
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.IMPORT {
+			continue
+		}
+		specs := gen.Specs[:0]
+		for _, spec := range gen.Specs {
+			if !unused[spec.(*ast.ImportSpec)] {
+				specs = append(specs, spec)
+			}
+		}
+		gen.Specs = specs
+	}
+}
+
+// usedPackages returns the names of the packages used by the declarations in code
+func usedPackages(code []byte) map[string]bool {
+	used := map[string]bool{}
+	file, err := goparser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), code...), 0)
+	if err != nil {
+		// the error is reported by AddCode
+		return used
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Obj == nil {
+				used[x.Name] = true
+			}
+		}
+		return true
+	})
+	return used
+}
+
+// Execute executes the template of the config, the result is the declarations to add
+func (g *Generator) Execute(c *Config) []byte {
+	src, err := ioutil.ReadFile(c.Template)
+	if err != nil {
+		errutil.Throw(err)
+	}
+	tmpl, err := gotemplate.New(filepath.Base(c.Template)).Funcs(g.Funcs()).Parse(string(src))
+	if err != nil {
+		errutil.Throw(err)
+	}
+
+	data := &Data{
+		Package: g.Pkg.Name,
+		Name:    c.Name,
+		Type:    g.NewType(c.Type),
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		errutil.Throw(err)
+	}
+	g.removeUnused(buf.Bytes())
+	return buf.Bytes()
+}
+
+// templateKey returns the template path relative to the directory of the output, or of the package,
+// so the owner is the same wherever pigo runs
+func templateKey(template string, first *Config) string {
+	dir := first.Dir
+	if first.Output != "" {
+		dir = filepath.Dir(first.Output)
+	}
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(template)
+	if err != nil {
+		return filepath.ToSlash(template)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(template)
+	}
+	rel, err := filepath.Rel(absDir, abs)
+	if err != nil {
+		return filepath.ToSlash(template)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (g *Generator) Generate(c *Config) error {
+	return g.GenerateAll([]*Config{c})
+}
+
+// GenerateAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func (g *Generator) GenerateAll(configs []*Config) (err error) {
+	defer func() { err = errutil.WithGenerator(err, "template") }()
+	defer errutil.Catch(&err)
+
+	if len(configs) == 0 {
+		return errors.New("no config given")
+	}
+	first := configs[0]
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, c := range configs {
+		// the declarations of a template are not known before it is executed
+		g.Own("template " + c.Type + " " + templateKey(c.Template, first))
+		g.TagName = c.TagName
+		if err := g.PrepareImports(c.Imports); err != nil {
+			return err
+		}
+		if err := g.AddCode(string(g.Execute(c))); err != nil {
+			return fmt.Errorf("%s: %w", c.Template, err)
+		}
+	}
+	return g.Output(first.Output)
+}
@@ -0,0 +1,116 @@
+package template
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/testutil"
+)
+
+func TestGolden(t *testing.T) {
+	testutil.Run(t, "testdata", func(c *testutil.Case) error {
+		config := &Config{}
+		if err := c.ReadConfig(config); err != nil {
+			return err
+		}
+		config.Dir = c.Dir
+		config.Template = c.Path(config.Template)
+		config.Output = c.Path(config.Output)
+
+		g := NewGenerator()
+		g.Parser = c.Parser
+		g.Outputs = c.Outputs
+		return g.Generate(config)
+	})
+}
+
+// the existing output of the rerun case is the golden one, so generating again changes nothing
+func TestGoldenRerun(t *testing.T) {
+	dir := filepath.Join("testdata", "rerun")
+	existing, err := ioutil.ReadFile(filepath.Join(dir, "zz_template.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := ioutil.ReadFile(filepath.Join(dir, "zz_template.go"+testutil.GoldenSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := generator.UnifiedDiff("existing", "golden", existing, golden); diff != "" {
+		t.Errorf("the rerun changes the output:\n%s", diff)
+	}
+}
+
+// the owner of a template is the same whether pigo runs in the package or in its parent
+func TestGenerateFromDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	model := filepath.Join(dir, "model")
+	if err := os.Mkdir(model, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"model.go", "fields.tmpl"} {
+		content, err := ioutil.ReadFile(filepath.Join("testdata", "fields", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(model, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	results := []string{}
+	for _, c := range []struct {
+		cwd    string
+		prefix string
+	}{
+		{model, ""},
+		{dir, "model/"},
+	} {
+		if err := os.Chdir(c.cwd); err != nil {
+			t.Fatal(err)
+		}
+		config := &Config{
+			Dir:      "./" + c.prefix,
+			Type:     "User",
+			Template: c.prefix + "fields.tmpl",
+			Name:     "userKeys",
+			TagName:  "json",
+			Output:   c.prefix + "zz_template.go",
+		}
+		if err := NewGenerator().Generate(config); err != nil {
+			t.Fatalf("in %s: %v", c.cwd, err)
+		}
+		result, err := ioutil.ReadFile(filepath.Join(model, "zz_template.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		results = append(results, string(result))
+	}
+	if !strings.Contains(results[0], "//pigo:owner template User fields.tmpl\n") {
+		t.Errorf("expect the template relative to the output in:\n%s", results[0])
+	}
+	if results[0] != results[1] {
+		t.Errorf("expect the same output, got:\n%s\nand:\n%s", results[0], results[1])
+	}
+}
+
+func TestGenerateAllEmpty(t *testing.T) {
+	g := NewGenerator()
+	g.Outputs = &generator.Outputs{}
+	if err := g.GenerateAll(nil); err == nil || !strings.Contains(err.Error(), "no config given") {
+		t.Errorf("expect no config error, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+type Base struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type User struct {
+	Base
+	Name     string  `json:"name"`
+	Email    *string `json:"email,omitempty"`
+	Password string  `json:"-"`
+	Tags     []string
+}
+
+func (u *User) Validate() error {
+	return nil
+}
+
+func (u User) DisplayName(prefix string) string {
+	return prefix + u.Name
+}
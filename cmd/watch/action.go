@@ -0,0 +1,140 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/cmd/run"
+	"github.com/lawrsp/pigo/cmd/scan"
+	"github.com/lawrsp/pigo/generator/configutil"
+	"github.com/urfave/cli"
+)
+
+var Usage = "run the generators again when the packages are changed"
+var Description = `run the //pigo: directives in the packages as scan does, or the tasks of the manifest given by --file,
+then watch the go files of the packages and the packages they import,
+after a change, only the outputs of the changed packages are generated again,
+the files are parsed again only when they are changed
+
+the errors are reported and watching goes on, until interrupted
+
+the packages are given as directories, "./..." means the current directory and all its sub directories`
+
+var Flags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "file,f",
+		Usage: "run the tasks of the manifest `FILE` instead of the directives",
+	},
+	cli.DurationFlag{
+		Name:  "interval",
+		Usage: "poll the files every `DURATION`",
+		Value: 500 * time.Millisecond,
+	},
+	cli.DurationFlag{
+		Name:  "debounce",
+		Usage: "wait for the files unchanged for `DURATION` before generating",
+		Value: 200 * time.Millisecond,
+	},
+}
+
+func Action(c *cli.Context) error {
+	w := &Watcher{
+		Parser:   cmdutil.Parser(c),
+		Interval: c.Duration("interval"),
+		Debounce: c.Duration("debounce"),
+		Jobs:     c.GlobalInt("jobs"),
+	}
+	header := cmdutil.Header(c)
+	verify := c.GlobalBool("verify")
+
+	if filePath := c.String("file"); filePath != "" {
+		w.Files = []string{filePath}
+		w.Find = func() ([]*Job, error) {
+			return TaskJobs(filePath, func(r *run.Runner) {
+				r.Parser = w.Parser
+				r.Header = header
+				r.Verify = verify
+			})
+		}
+	} else {
+		patterns := []string(c.Args())
+		if len(patterns) == 0 {
+			patterns = []string{"."}
+		}
+		dirs, err := scan.Dirs(patterns)
+		if err != nil {
+			return err
+		}
+		s := scan.NewScanner()
+		s.Parser = w.Parser
+		s.Header = header
+		s.Verify = verify
+		w.Dirs = dirs
+		w.Find = func() ([]*Job, error) {
+			return ScanJobs(s, dirs)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return w.Run(ctx)
+}
+
+// ScanJobs returns a job of each group of directives in the directories
+func ScanJobs(s *scan.Scanner, dirs []string) ([]*Job, error) {
+	jobs := []*Job{}
+	for _, dir := range dirs {
+		groups, err := s.Groups(dir)
+		if err != nil {
+			return jobs, err
+		}
+		for _, group := range groups {
+			group := group
+			jobs = append(jobs, &Job{
+				Name:   fmt.Sprintf("scan %s: %s -> %s", dir, group.Generator, group.Output),
+				Dir:    group.Dir,
+				Output: group.Output,
+				Run: func() error {
+					return s.RunGroup(group)
+				},
+			})
+		}
+	}
+	return jobs, nil
+}
+
+// TaskJobs reads the manifest and returns a job of each task, setup configures the runner of the tasks
+func TaskJobs(filePath string, setup func(r *run.Runner)) ([]*Job, error) {
+	manifest := &run.Manifest{}
+	if err := configutil.ReadConfig(filePath, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != "" && manifest.Version != "1" {
+		return nil, fmt.Errorf("Version not supported")
+	}
+
+	r := run.NewRunner()
+	setup(r)
+	outputs, err := r.Prepare(manifest, filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	jobs := []*Job{}
+	for i, t := range manifest.Tasks {
+		t := t
+		jobs = append(jobs, &Job{
+			Name:   fmt.Sprintf("task %d -> %s", i+1, outputs[i]),
+			Dir:    filepath.Dir(outputs[i]),
+			Output: outputs[i],
+			Run: func() error {
+				return r.RunTask(t)
+			},
+		})
+	}
+	return jobs, nil
+}
@@ -0,0 +1,309 @@
+package watch
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// Job is a generation run again when the packages it reads are changed
+type Job struct {
+	// Name is the job in the log, e.g. "scan model: checker -> model/zz_generated.go"
+	Name string
+	// Dir is the package directory of the job, the job reads it and the packages it imports
+	Dir string
+	// Output is parsed again after the job, it is changed by the job
+	Output string
+	Run    func() error
+
+	// the package directories read, see cmdutil.Deps
+	reads []string
+}
+
+// Watcher polls the go files of the jobs, and runs the jobs again when they are changed,
+// the files are parsed once by the shared parser until they are changed, see parser.Parser.Cache
+type Watcher struct {
+	Parser *parser.Parser
+	// Find returns the jobs to run, it is called again after a change, so the new directives and tasks are found
+	Find func() ([]*Job, error)
+	// Dirs are watched besides the directories of the jobs, e.g. the packages without directives yet
+	Dirs []string
+	// Files are watched besides the go files, all jobs run again when they are changed, e.g. pigo.yaml
+	Files []string
+	// Interval is the time between the polls
+	Interval time.Duration
+	// Debounce is the time the files should stay unchanged before the jobs run, so a burst of saves runs them once
+	Debounce time.Duration
+	// Jobs is the number of jobs run at once, runtime.GOMAXPROCS if 0, see cmdutil.Schedule
+	Jobs int
+	// Logf reports the jobs and their errors, log.Printf if nil
+	Logf func(format string, args ...interface{})
+
+	// the running jobs hold it for reading, forget holds it for writing,
+	// so the resolved types are not dropped while the jobs use them
+	mu sync.RWMutex
+}
+
+// stamp is the state of a file, a change of either is a change of the file
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type snapshot map[string]stamp
+
+// Run runs all jobs, then the jobs of the changed packages after each change, until ctx is done,
+// the errors of the jobs are reported and watching goes on
+func (w *Watcher) Run(ctx context.Context) error {
+	w.Parser.Cache = true
+	jobs := w.find()
+	deps := w.deps(jobs)
+	// the snapshot is taken before the run, the files saved while running are changes of the next poll
+	last := w.snapshot(deps)
+	w.run(jobs, jobs)
+	last = w.withOutputs(last, jobs, deps)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := w.snapshot(deps)
+		if len(changed(last, current)) == 0 {
+			continue
+		}
+		// wait for the end of the burst
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(w.Debounce):
+			}
+			next := w.snapshot(deps)
+			if len(changed(current, next)) == 0 {
+				break
+			}
+			current = next
+		}
+
+		files := changed(last, current)
+		w.forget(files...)
+		jobs = w.find()
+		deps = w.deps(jobs)
+		last = w.snapshot(deps)
+		// the files saved since the burst, and the ones of the new directories
+		more := changed(current, last)
+		w.forget(more...)
+		selected := w.affected(jobs, append(files, more...))
+		w.run(jobs, selected)
+		last = w.withOutputs(last, selected, deps)
+	}
+}
+
+// withOutputs returns the snapshot before the jobs run, with the states of their outputs after it,
+// the outputs written are not changes to run for, the other files saved while running are
+func (w *Watcher) withOutputs(before snapshot, jobs []*Job, deps []string) snapshot {
+	after := w.snapshot(deps)
+	for _, job := range jobs {
+		if job.Output == "" {
+			continue
+		}
+		name := absPath(job.Output)
+		if st, ok := after[name]; ok {
+			before[name] = st
+		} else {
+			delete(before, name)
+		}
+	}
+	return before
+}
+
+func (w *Watcher) logf(format string, args ...interface{}) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
+func (w *Watcher) find() []*Job {
+	jobs, err := w.Find()
+	if err != nil {
+		w.logf("watch: %v", err)
+	}
+	w.setReads(jobs)
+	return jobs
+}
+
+// setReads sets the package directories read by the jobs, they are kept nil if unknown
+func (w *Watcher) setReads(jobs []*Job) {
+	dirs := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		dirs = append(dirs, absPath(job.Dir))
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	deps, err := cmdutil.Deps(w.Parser.ImportContext, dirs)
+	if err != nil {
+		w.logf("watch: %v", err)
+		return
+	}
+	for i, job := range jobs {
+		job.reads = deps[dirs[i]]
+	}
+}
+
+// run runs the selected jobs, as in the order of all jobs, see cmdutil.Schedule
+func (w *Watcher) run(all []*Job, selected []*Job) {
+	run := map[*Job]bool{}
+	for _, job := range selected {
+		run[job] = true
+	}
+	jobs := []*cmdutil.Job{}
+	names := []string{}
+	for _, job := range all {
+		if !run[job] {
+			continue
+		}
+		job := job
+		names = append(names, job.Name)
+		jobs = append(jobs, &cmdutil.Job{
+			Output: job.Output,
+			Reads:  job.reads,
+			Run: func() error {
+				w.logf("watch %s", job.Name)
+				w.mu.RLock()
+				err := job.Run()
+				w.mu.RUnlock()
+				// the output is changed, and its ast is changed by the generator even if it fails
+				if job.Output != "" {
+					w.forget(job.Output)
+				}
+				return err
+			},
+		})
+	}
+	for i, err := range cmdutil.Schedule(w.Jobs, jobs) {
+		if err != nil {
+			w.logf("watch %s: %v", names[i], err)
+		}
+	}
+}
+
+// forget drops the files from the parser when no job is running, see parser.Parser.Forget
+func (w *Watcher) forget(files ...string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.Parser.Forget(files...)
+}
+
+// affected returns the jobs reading the changed files, all jobs if a watched file is changed,
+// and the jobs of which the packages read are unknown
+func (w *Watcher) affected(jobs []*Job, files []string) []*Job {
+	dirs := map[string]bool{}
+	for _, file := range files {
+		for _, watched := range w.Files {
+			if sameFile(file, watched) {
+				return jobs
+			}
+		}
+		dirs[absPath(filepath.Dir(file))] = true
+	}
+
+	result := []*Job{}
+	for _, job := range jobs {
+		if job.reads == nil {
+			result = append(result, job)
+			continue
+		}
+		for _, dir := range job.reads {
+			if dirs[dir] {
+				result = append(result, job)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// deps returns the directories to watch
+func (w *Watcher) deps(jobs []*Job) []string {
+	seen := map[string]bool{}
+	for _, dir := range w.Dirs {
+		seen[absPath(dir)] = true
+	}
+	for _, job := range jobs {
+		seen[absPath(job.Dir)] = true
+		for _, dir := range job.reads {
+			seen[dir] = true
+		}
+	}
+	return generator.SortedKeys(seen)
+}
+
+// snapshot returns the states of the go files in the directories and the watched files
+func (w *Watcher) snapshot(dirs []string) snapshot {
+	s := snapshot{}
+	for _, dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+				continue
+			}
+			s[filepath.Join(dir, info.Name())] = stamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	for _, name := range w.Files {
+		if info, err := os.Stat(name); err == nil {
+			s[absPath(name)] = stamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return s
+}
+
+// changed returns the files added, removed or changed from a to b, sorted
+func changed(a, b snapshot) []string {
+	files := []string{}
+	for name, st := range b {
+		if old, ok := a[name]; !ok || !old.modTime.Equal(st.modTime) || old.size != st.size {
+			files = append(files, name)
+		}
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func absPath(name string) string {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return filepath.Clean(name)
+	}
+	return abs
+}
+
+func sameFile(a, b string) bool {
+	return absPath(a) == absPath(b)
+}
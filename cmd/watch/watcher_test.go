@@ -0,0 +1,249 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+type counter struct {
+	sync.Mutex
+	runs map[string]int
+}
+
+func (c *counter) add(name string) {
+	c.Lock()
+	defer c.Unlock()
+	c.runs[name]++
+}
+
+func (c *counter) get(name string) int {
+	c.Lock()
+	defer c.Unlock()
+	return c.runs[name]
+}
+
+func writeFile(t *testing.T, name string, content string) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func waitFor(t *testing.T, what string, ok func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !ok() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/w\n\ngo 1.18\n")
+	writeFile(t, filepath.Join(a, "a.go"), "package a\n")
+	writeFile(t, filepath.Join(b, "b.go"), "package b\n")
+
+	c := &counter{runs: map[string]int{}}
+	var logs []string
+	var mu sync.Mutex
+	w := &Watcher{
+		Parser:   parser.NewParser(),
+		Interval: 10 * time.Millisecond,
+		Debounce: 100 * time.Millisecond,
+		Logf: func(format string, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			logs = append(logs, fmt.Sprintf(format, args...))
+		},
+		Find: func() ([]*Job, error) {
+			return []*Job{
+				{Name: "a", Dir: a, Output: filepath.Join(a, "zz_generated.go"), Run: func() error {
+					c.add("a")
+					// the output of a job is not a change
+					writeFile(t, filepath.Join(a, "zz_generated.go"), "package a\n\n// "+strings.Repeat("x", c.get("a"))+"\n")
+					return nil
+				}},
+				{Name: "b", Dir: b, Run: func() error {
+					c.add("b")
+					return errors.New("failed")
+				}},
+			}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	waitFor(t, "the first runs", func() bool { return c.get("a") == 1 && c.get("b") == 1 })
+
+	// a burst of saves runs the jobs once
+	for i := 0; i < 3; i++ {
+		writeFile(t, filepath.Join(a, "a.go"), "package a\n"+strings.Repeat("\n", i+1))
+		time.Sleep(20 * time.Millisecond)
+	}
+	waitFor(t, "a after the change of a", func() bool { return c.get("a") == 2 })
+	time.Sleep(300 * time.Millisecond)
+	if c.get("a") != 2 || c.get("b") != 1 {
+		t.Errorf("expect a run once and b not run, got a %d, b %d", c.get("a"), c.get("b"))
+	}
+
+	// the errors are reported and watching goes on
+	writeFile(t, filepath.Join(b, "new.go"), "package b\n")
+	waitFor(t, "b after the change of b", func() bool { return c.get("b") == 2 })
+	writeFile(t, filepath.Join(b, "new.go"), "package b\n\n")
+	waitFor(t, "b after the second change of b", func() bool { return c.get("b") == 3 })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c.get("a") != 2 {
+		t.Errorf("expect a not run by the changes of b, got %d", c.get("a"))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !strings.Contains(strings.Join(logs, "\n"), "watch b: failed") {
+		t.Errorf("expect the error reported, got %v", logs)
+	}
+}
+
+// the files saved while the jobs run are not taken as their outputs
+func TestWatcherSaveWhileRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/w\n\ngo 1.18\n")
+	writeFile(t, filepath.Join(a, "a.go"), "package a\n")
+	writeFile(t, filepath.Join(b, "b.go"), "package b\n")
+
+	c := &counter{runs: map[string]int{}}
+	w := &Watcher{
+		Parser:   parser.NewParser(),
+		Interval: 10 * time.Millisecond,
+		Debounce: 50 * time.Millisecond,
+		Logf:     func(format string, args ...interface{}) {},
+		Find: func() ([]*Job, error) {
+			return []*Job{
+				{Name: "a", Dir: a, Output: filepath.Join(a, "zz_generated.go"), Run: func() error {
+					c.add("a")
+					writeFile(t, filepath.Join(a, "zz_generated.go"), "package a\n\n// "+strings.Repeat("x", c.get("a"))+"\n")
+					if c.get("a") == 1 {
+						// saved by the user while generating
+						writeFile(t, filepath.Join(b, "b.go"), "package b\n\n// saved\n")
+					}
+					return nil
+				}},
+				{Name: "b", Dir: b, Run: func() error {
+					c.add("b")
+					return nil
+				}},
+			}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+
+	waitFor(t, "b after the save while running", func() bool { return c.get("b") == 2 })
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if c.get("a") != 1 || c.get("b") != 2 {
+		t.Errorf("expect a run once and b twice, got a %d, b %d", c.get("a"), c.get("b"))
+	}
+}
+
+// the output of a job is forgotten after the other running jobs, which keep the types they resolved
+func TestWatcherForgetAfterRunning(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir, _ = filepath.EvalSymlinks(dir)
+	a := filepath.Join(dir, "a")
+	b := filepath.Join(dir, "b")
+	writeFile(t, filepath.Join(dir, "go.mod"), "module example.com/w\n\ngo 1.18\n")
+	writeFile(t, filepath.Join(a, "a.go"), "package a\n")
+	writeFile(t, filepath.Join(b, "b.go"), "package b\n\nimport \"strings\"\n\nvar B strings.Builder\n")
+
+	p := parser.NewParser()
+	aDone := make(chan struct{})
+	result := make(chan error, 1)
+	w := &Watcher{
+		Parser:   p,
+		Interval: 10 * time.Millisecond,
+		Debounce: 50 * time.Millisecond,
+		Jobs:     2,
+		Logf:     func(format string, args ...interface{}) {},
+		Find: func() ([]*Job, error) {
+			return []*Job{
+				{Name: "a", Dir: a, Output: filepath.Join(a, "zz_generated.go"), Run: func() error {
+					writeFile(t, filepath.Join(a, "zz_generated.go"), "package a\n\n// x\n")
+					close(aDone)
+					return nil
+				}},
+				{Name: "b", Dir: b, Run: func() error {
+					pkg, err := p.ParsePackageDir(b)
+					if err != nil {
+						result <- err
+						return err
+					}
+					expr, _ := parser.ParseExpr("strings.Builder")
+					pkg.ReduceType(expr)
+					_, before, _ := p.ImportScope("strings", b)
+					<-aDone
+					time.Sleep(100 * time.Millisecond)
+					_, after, _ := p.ImportScope("strings", b)
+					if before == nil || after != before {
+						result <- errors.New("the scope is dropped while running")
+					} else {
+						result <- nil
+					}
+					return nil
+				}},
+			}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
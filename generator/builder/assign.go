@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -143,7 +144,7 @@ func StructAssign(bd Builder, srcV, dstV *Variable, srcFields, dstFields *FieldL
 				// log.Printf("%s = %s, dstV anonymous: %v", srcV.Name(), dstV.Name(), dstV.IsAnonymous())
 				FollowTPaths(bd, srcV, dstV, nil, paths)
 			} else {
-				log.Fatalf("cannot assgin field %s(%s) to %s(%s) knownTpaths(%v)",
+				errutil.Throwf("cannot assgin field %s(%s) to %s(%s) knownTpaths(%v)",
 					srcFd.Name, srcFd.Field, dstFd.Name, dstFd.Field, knownTpaths)
 			}
 		}
@@ -152,7 +153,7 @@ func StructAssign(bd Builder, srcV, dstV *Variable, srcFields, dstFields *FieldL
 
 func TryDirectAssign(bd Builder, srcV, dstV *Variable, ev *Variable, knownTpaths []*parser.TPath) bool {
 	if srcV == nil || !srcV.IsVisible() {
-		log.Fatalf("some bugs: source variable is nil")
+		errutil.Throwf("some bugs: source variable is nil")
 		return false
 	}
 	if paths, ok := parser.TypeToType(srcV.Type, dstV.Type, knownTpaths); ok {
@@ -253,7 +254,7 @@ func (b *StructSetterBuilder) structAssign(srcV, dstV *Variable, srcFields, dstF
 				tpd.Follow(srcV, dstV, paths)
 				b.Add(tpd)
 			} else {
-				log.Fatalf("cannot assgin field %s(%s) to %s(%s)", srcFd.Name, srcFd.Field, dstFd.Name, dstFd.Field)
+				errutil.Throwf("cannot assgin field %s(%s) to %s(%s)", srcFd.Name, srcFd.Field, dstFd.Name, dstFd.Field)
 			}
 		}
 	}
@@ -38,8 +38,8 @@ func ExampleStructAssign() {
 	// fmt.Println(code)
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -185,8 +185,8 @@ func ExampleStructAssign2() {
 	// fmt.Println(code)
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -242,8 +242,8 @@ func ExampleStructAssign3() {
 	// fmt.Println(code)
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"go/ast"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -58,7 +59,10 @@ func (b *FuncBufferBuilder) Printf(format string, args ...interface{}) {
 
 func (b *FuncBufferBuilder) Decl() *ast.FuncDecl {
 	p := parser.NewParser()
-	file := p.ParseFileContent("_buffer", fmt.Sprintf("package buffer\n %s", b.Bytes()))
+	file, err := p.ParseFileContent("_buffer", fmt.Sprintf("package buffer\n %s", b.Bytes()))
+	if err != nil {
+		errutil.Throwf("function %s: %w", b.name, err)
+	}
 	var decl *ast.FuncDecl
 	parser.WalkFile(file, parser.NewFuncDeclWalker(func(fd *ast.FuncDecl) bool {
 		if fd.Name.Name == b.name {
@@ -10,8 +10,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
-	"github.com/lawrsp/pigo/generator/printutil"
 )
 
 type ScopeLevel int
@@ -62,7 +62,7 @@ func (b *baseBuilder) File() *parser.File {
 }
 func (b *baseBuilder) addVariable(v *Variable) bool {
 	if v.isVisible {
-		log.Fatalf("add visible variable: %s(%s)", v.Name(), v.Type)
+		errutil.Throwf("add visible variable: %s(%s)", v.Name(), v.Type)
 	}
 	if ok := b.variables.Insert(v, 0); !ok {
 		return false
@@ -84,14 +84,14 @@ func (b *baseBuilder) Variables() *VariableList {
 	return b.variables
 }
 func (b *baseBuilder) Add(bd Builder) {
-	log.Fatalf("not implemented Add")
+	errutil.Throwf("not implemented Add")
 }
 func (b *baseBuilder) Block() *BlockBuilder {
-	log.Fatalf("not implemented Block()")
+	errutil.Throwf("not implemented Block()")
 	return nil
 }
 func (b *baseBuilder) Bytes() []byte {
-	log.Fatalf("not implemented Bytes()")
+	errutil.Throwf("not implemented Bytes()")
 	return nil
 }
 
@@ -109,7 +109,7 @@ func NewFile(outer Builder, file *parser.File) *FileBuilder {
 	}
 
 	if file != nil && file.BelongTo != nil && file.BelongTo != pkg {
-		log.Fatalf("cannot create builder with different package")
+		errutil.Throwf("cannot create builder with different package")
 	}
 
 	base := newbaseBuilder(outer, pkg, file)
@@ -129,7 +129,7 @@ func (b *FileBuilder) AddImport(name, path string) {
 	for _, spec := range b.file.Imports {
 		oldPath, err := strconv.Unquote(spec.Path.Value)
 		if err != nil {
-			log.Fatalf("get old path error:%v", err)
+			errutil.Throwf("get old path error:%v", err)
 		}
 		var oldName string
 		if spec.Name != nil {
@@ -141,7 +141,7 @@ func (b *FileBuilder) AddImport(name, path string) {
 
 		if oldName == name {
 			if oldPath != path {
-				log.Fatalf("cannot add import %s with same name and different path:\n%s\n%s ", name, oldPath, path)
+				errutil.Throwf("cannot add import %s with same name and different path:\n%s\n%s ", name, oldPath, path)
 			}
 			return
 		}
@@ -184,9 +184,13 @@ func getType(e ast.Expr) (int, string) {
 	case *ast.StarExpr:
 		_, name := getType(me.X)
 		return 2, name
+	case *ast.IndexExpr:
+		//generic receiver, the names of type parameters do not matter
+		return getType(me.X)
+	case *ast.IndexListExpr:
+		return getType(me.X)
 	default:
-		printutil.PrintNodef(me, "")
-		log.Fatalf("not supported")
+		errutil.Throwf("not supported %s", parser.ExprToString(me))
 
 	}
 	return -1, ""
@@ -219,12 +223,8 @@ func funcDeclEqual(x *ast.FuncDecl, y *ast.FuncDecl) bool {
 		return false
 	}
 
-	xR := x.Recv.List[0]
-	yR := y.Recv.List[0]
-
-	// log.Printf("%s == %s ? %v==============", xR.Names[0].Name, yR.Names[0].Name, xR.Names[0].Name == yR.Names[0].Name)
-
-	return xR.Names[0].Name == yR.Names[0].Name && exprEqual(xR.Type, yR.Type)
+	// a method is of the receiver type, the receiver name, or none, does not matter
+	return exprEqual(x.Recv.List[0].Type, y.Recv.List[0].Type)
 
 }
 
@@ -251,7 +251,7 @@ func (b *FileBuilder) Add(ab Builder) {
 		fd := x.Decl()
 		b.AddFuncDecl(fd)
 	default:
-		log.Fatalf("not supported %s", ab.String())
+		errutil.Throwf("not supported %s", ab.String())
 	}
 }
 
@@ -269,7 +269,7 @@ func (b *BlockBuilder) Block() *BlockBuilder {
 func (b *BlockBuilder) Bytes() []byte {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), b.Body); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %v", err)
 	}
 
 	return buf.Bytes()
@@ -312,7 +312,7 @@ func (b *FuncBuilder) Block() *BlockBuilder {
 func (b *FuncBuilder) Bytes() []byte {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), b.Decl); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %v", err)
 	}
 	return buf.Bytes()
 }
@@ -331,7 +331,7 @@ func NewFunction(outer Builder, receiver parser.Type, fnt parser.Type, ew ErrorW
 	allVars := getAllVariables(outer)
 
 	if fnt.Name() == "" {
-		log.Fatalf("func type without any name: %s", fnt)
+		errutil.Throwf("func type without any name: %s", fnt)
 	}
 
 	decl := &ast.FuncDecl{}
@@ -344,7 +344,7 @@ func NewFunction(outer Builder, receiver parser.Type, fnt parser.Type, ew ErrorW
 
 	underFnt, ok := fnt.Underlying().(*parser.FuncType)
 	if !ok {
-		log.Fatalf("the type is not a funcType: %s", fnt)
+		errutil.Throwf("the type is not a funcType: %s", fnt)
 	}
 
 	if receiver == nil && underFnt.Receiver != nil {
@@ -2,8 +2,8 @@ package builder
 
 import (
 	"go/ast"
-	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -32,7 +32,7 @@ func (wrapper *funcErrorWrapper) WrapError(b Builder, ev *Variable) *VariableLis
 	underFunc := wrapper.Type.Underlying().(*parser.FuncType)
 	exprs, ok := exprListFromFields(underFunc.Params, args.Getter(READ_MODE))
 	if !ok {
-		log.Fatalf("cannot wrapp error with func %s(arguments not enough)", wrapper.Type)
+		errutil.Throwf("cannot wrapp error with func %s(arguments not enough)", wrapper.Type)
 	}
 	expr := callExpr(parser.TypeExprInFile(wrapper.Type, file), exprs)
 	nev.SetName("")
@@ -59,7 +59,7 @@ func (wrapper *funcErrorWrapper) Wrap(b Builder, ev *Variable) *Variable {
 	underFunc := wrapper.Type.Underlying().(*parser.FuncType)
 	exprs, ok := exprListFromFields(underFunc.Params, args.Getter(READ_MODE))
 	if !ok {
-		log.Fatalf("cannot wrapp error with func %s(arguments not enough)", wrapper.Type)
+		errutil.Throwf("cannot wrapp error with func %s(arguments not enough)", wrapper.Type)
 	}
 	expr := callExpr(parser.TypeExprInFile(wrapper.Type, file), exprs)
 	nev.SetName("")
@@ -98,9 +98,9 @@ func NewErrorWrapper(input interface{}) ErrorWrapper {
 	case nil:
 		return nil
 	default:
-		log.Fatalf("doonot support input %v", i)
+		errutil.Throwf("doonot support input %v", i)
 		return nil
 	}
-	log.Fatalf("doonot support input %v", input)
+	errutil.Throwf("doonot support input %v", input)
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 	"go/ast"
 	"strings"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -151,7 +152,10 @@ func VariableName(name string) string {
 }
 
 func GenNameFromType(typ ast.Expr) string {
-	t := parser.ParseType(typ)
+	t, err := parser.ParseType(typ)
+	if err != nil {
+		errutil.Throw(err)
+	}
 	name := VariableName(t.String())
 	return name
 }
@@ -3,8 +3,8 @@ package builder
 import (
 	"go/ast"
 	"go/token"
-	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -118,7 +118,8 @@ func (b *TPathBuilder) assignTargetVar(inBuilder Builder, dstV *Variable, midV *
 
 		value = midV.PointerValueExpr()
 	} else {
-		if b.checkDiff {
+		//funcs cannot be compared, they are always set
+		if b.checkDiff && !parser.IsFuncType(dstV.Type) {
 			// if  v != midV {
 			//		v = midV
 			// }
@@ -265,7 +266,7 @@ func (bd *TPathBuilder) Follow(srcV, dstV *Variable, paths []*parser.TPath) {
 
 	//src should not be nil
 	if srcV == nil {
-		log.Fatalf("srcV is nil")
+		errutil.Throwf("srcV is nil")
 	}
 
 	// for i, fp := range paths {
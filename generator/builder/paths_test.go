@@ -52,8 +52,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -84,8 +84,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -122,8 +122,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -166,8 +166,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -207,8 +207,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -252,8 +252,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -293,8 +293,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -353,8 +353,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -392,8 +392,8 @@ package tt
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	fileBuilder := NewFile(nil, file)
@@ -437,3 +437,62 @@ package tt
 	//	return t2List, nil
 	//}
 }
+
+//T1, []T2 => (call variadic) => T1
+func ExampleCallStmtBuilder_variadic() {
+	code := `
+package tt
+
+`
+	p := parser.NewParser()
+
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file = pkg.Files[0]
+
+	fileBuilder := NewFile(nil, file)
+
+	t1 := parser.TypeWithName(nil, "T1")
+	t2 := parser.TypeWithName(nil, "T2")
+	st2 := parser.TypeWithSlice(t2)
+
+	variadic := &parser.FuncType{Variadic: true}
+	variadic.Params = []*parser.Field{
+		parser.NewField(t1, "t", ""),
+		parser.NewField(st2, "opts", ""),
+	}
+	variadic.Results = []*parser.Field{
+		parser.NewField(t1, "", ""),
+		parser.NewField(parser.ErrorType(), "", ""),
+	}
+	call := parser.TypeWithName(variadic, "withOptions")
+
+	fnt := &parser.FuncType{}
+	fnt.Params = []*parser.Field{
+		parser.NewField(t1, "t", ""),
+		parser.NewField(st2, "opts", ""),
+	}
+	fnt.Results = []*parser.Field{
+		parser.NewField(t1, "", ""),
+		parser.NewField(parser.ErrorType(), "", ""),
+	}
+	bd := NewFunction(fileBuilder, nil, parser.TypeWithName(fnt, "test11"), nil)
+	params := NewVariableList()
+	params.Add(GetVariable(bd, t1, READ_MODE, Scope_Function))
+	params.Add(GetVariable(bd, st2, READ_MODE, Scope_Function))
+
+	results := AddCallStmt(bd, params, call)
+	errV := results.GetByType(parser.ErrorType(), READ_MODE)
+	AddCheckReturn(bd, errV.CheckNilExpr(false), errV)
+	AddSuccessReturn(bd)
+
+	fmt.Println(string(bd.Bytes()))
+	// Output:
+	// func test11(t T1, opts []T2) (T1, error) {
+	//	t1, err := withOptions(t, opts...)
+	//	if err != nil {
+	//		return T1{}, err
+	//	}
+	//	return t1, nil
+	//}
+}
@@ -5,8 +5,8 @@ import (
 	"go/ast"
 	"go/format"
 	"go/token"
-	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -27,7 +27,7 @@ func (b *ForRangeBuilder) Block() *BlockBuilder {
 func (b *ForRangeBuilder) Bytes() []byte {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), b.block); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %v", err)
 	}
 	return buf.Bytes()
 }
@@ -140,7 +140,7 @@ func (b *CallStmtBuilder) Stmt() ast.Stmt {
 func (b *CallStmtBuilder) Bytes() []byte {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), b.stmt); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %v", err)
 	}
 
 	return buf.Bytes()
@@ -152,17 +152,29 @@ func (b *CallStmtBuilder) Call(vl *VariableList, ignores []int) *VariableList {
 
 	underFunc, ok := fnt.Underlying().(*parser.FuncType)
 	if !ok {
-		log.Fatalf("not a function type: %s", fnt)
+		errutil.Throwf("not a function type: %s", fnt)
 	}
 
 	//oder: inner to outer
 	args := vl.Concat(getAllVariables(b))
 	//args.debug()
-	paramExprList, ok := exprListFromFields(underFunc.Params, args.Getter(READ_MODE))
+	params := underFunc.Params
+	if underFunc.Variadic {
+		params = params[:len(params)-1]
+	}
+	paramExprList, ok := exprListFromFields(params, args.Getter(READ_MODE))
 	if !ok {
-		log.Fatalf("call expr arguments not enough: %s", fnt)
+		errutil.Throwf("call expr arguments not enough: %s", fnt)
 	}
 	callExpr := callExpr(parser.TypeExprInFile(fnt, file), paramExprList)
+	if underFunc.Variadic {
+		//f(a, xs...) if there is a []T, or f(a) without the variadic arguments
+		last := underFunc.Params[len(params)]
+		if v := args.Getter(READ_MODE).Get(len(params), last.Type); v != nil {
+			callExpr.Args = append(callExpr.Args, v.Ident())
+			callExpr.Ellipsis = 1
+		}
+	}
 
 	//make result variables
 	var resultList []ast.Expr
@@ -213,7 +225,7 @@ func (b *CallStmtBuilder) Call(vl *VariableList, ignores []int) *VariableList {
 func NewCallStmt(outer Builder, fnt parser.Type) *CallStmtBuilder {
 	block := outer.Block()
 	if block == nil {
-		log.Fatalf("builder does not have a block")
+		errutil.Throwf("builder does not have a block")
 	}
 	return &CallStmtBuilder{baseBuilder: newBaseFromOuter(block), fnt: fnt}
 }
@@ -233,7 +245,7 @@ func NewReturnStmt(outer Builder, results *VariableList) *ReturnStmtBuilder {
 		function, _ = x.(*FuncBuilder)
 	}
 	if function == nil {
-		log.Fatalf("context donnot support return")
+		errutil.Throwf("context donnot support return")
 	}
 
 	resultExpr := []ast.Expr{}
@@ -280,7 +292,7 @@ func (b *IfStmtBuilder) Block() *BlockBuilder {
 func (b *IfStmtBuilder) Bytes() []byte {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), b.block); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %v", err)
 	}
 	return buf.Bytes()
 }
@@ -338,7 +350,7 @@ func (b *IfStmtBuilder) Else() *BlockBuilder {
 func NewIfBuilderWithSrc(outer Builder, src string) Builder {
 	cond, err := parser.ParseExpr(src)
 	if err != nil {
-		log.Fatalf("if statement src error: %s", src)
+		errutil.Throwf("if statement src error: %s", src)
 		return nil
 	}
 
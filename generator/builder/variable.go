@@ -6,6 +6,7 @@ import (
 	"go/token"
 	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 )
 
@@ -129,7 +130,7 @@ func (v *Variable) Ident() ast.Expr {
 	}
 
 	// panic("variable has no name and no expr")
-	log.Fatalf("variable has no name and no expr")
+	errutil.Throwf("variable has no name and no expr")
 	return nil
 }
 
@@ -147,7 +148,7 @@ func (v *Variable) SetExpr(expr ast.Expr) {
 func (v *Variable) SetExprSrc(str string) {
 	expr, err := parser.ParseExpr(str)
 	if err != nil {
-		log.Fatalf("expr %s parse error: %v", str, err)
+		errutil.Throwf("expr %s parse error: %v", str, err)
 	}
 
 	v.expr = expr
@@ -212,7 +213,7 @@ func (v *Variable) DotExpr(name string) ast.Expr {
 
 	dot, err := parser.ParseExpr(name)
 	if err != nil {
-		log.Fatalf("%s dot %s error: %v", v.name, name, err)
+		errutil.Throwf("%s dot %s error: %v", v.name, name, err)
 	}
 
 	return DotExpr(ast.NewIdent(v.name), dot)
@@ -221,7 +222,7 @@ func (v *Variable) DotExpr(name string) ast.Expr {
 func (v *Variable) DotTypeInFile(dotSrc string, file *parser.File) parser.Type {
 	dot, err := parser.ParseExpr(dotSrc)
 	if err != nil {
-		log.Fatalf("%s dot %s error: %v", v.name, dotSrc, err)
+		errutil.Throwf("%s dot %s error: %v", v.name, dotSrc, err)
 	}
 
 	expr := parser.TypeExprInFile(v.Type, file)
@@ -102,8 +102,8 @@ type A struct {
 `
 	p := parser.NewParser()
 
-	file := p.ParseFileContent("test", code)
-	pkg := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
+	file, _ := p.ParseFileContent("test", code)
+	pkg, _ := parser.NewPackage(p, "fake", "./fake.go", "", []*parser.File{file})
 	file = pkg.Files[0]
 
 	ta := file.ReduceType(ast.NewIdent("A"))
@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+
+	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
+)
+
+// AddCode adds the declarations in code to the output file, the code has no package clause and no imports,
+// see PrepareImports, the funcs replace the ones of the same name and receiver, see builder.FileBuilder.AddFuncDecl,
+// and the types, vars and consts replace the ones of the same names where they are
+// the declarations are written as in code, with the comments
+func (g *Generator) AddCode(code string) (err error) {
+	defer errutil.Catch(&err)
+
+	g.PrepareParser()
+	file, err := goparser.ParseFile(g.Parser.FileSet, "", "package p\n"+code, goparser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("parse code: %w", err)
+	}
+	g.comments = append(g.comments, file.Comments...)
+
+	if g.parsed == nil {
+		g.parsed = map[ast.Decl]bool{}
+	}
+	fb := builder.NewFile(nil, g.File)
+	for _, decl := range file.Decls {
+		g.parsed[decl] = true
+		switch x := decl.(type) {
+		case *ast.FuncDecl:
+			fb.AddFuncDecl(x)
+		case *ast.GenDecl:
+			if x.Tok == token.IMPORT {
+				return errors.New("code should not import, use the imports")
+			}
+			g.addGenDecl(x)
+		}
+	}
+	return nil
+}
+
+func (g *Generator) addGenDecl(gen *ast.GenDecl) {
+	name := declName(gen)
+	for i, decl := range g.File.File.Decls {
+		if old, ok := decl.(*ast.GenDecl); ok && declName(old) == name {
+			g.File.File.Decls[i] = gen
+			return
+		}
+	}
+	g.File.File.Decls = append(g.File.File.Decls, gen)
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	new := "a\nb\nc\nD\ne\nf\ng\nh\ni\n"
+
+	expect := `--- a/x.go
++++ b/x.go
+@@ -1,8 +1,9 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+ h
++i
+`
+	if diff := UnifiedDiff("a/x.go", "b/x.go", []byte(old), []byte(new)); diff != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, diff)
+	}
+
+	if diff := UnifiedDiff("a", "b", []byte(old), []byte(old)); diff != "" {
+		t.Errorf("expect no diff, got:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	old := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+	new := "0\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n"
+
+	expect := `--- a
++++ b
+@@ -1,4 +1,4 @@
+-1
++0
+ 2
+ 3
+ 4
+@@ -9,4 +9,3 @@
+ 9
+ 10
+ 11
+-12
+`
+	if diff := UnifiedDiff("a", "b", []byte(old), []byte(new)); diff != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, diff)
+	}
+}
+
+func TestCheckOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "out.go")
+	if err := ioutil.WriteFile(output, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteOutput(output, []byte("package a\n"), true); err != nil {
+		t.Errorf("expect up to date, got %v", err)
+	}
+
+	err = WriteOutput(output, []byte("package b\n"), true)
+	var drift *DriftError
+	if !errors.As(err, &drift) {
+		t.Fatalf("expect DriftError, got %v", err)
+	}
+
+	content, _ := ioutil.ReadFile(output)
+	if string(content) != "package a\n" {
+		t.Errorf("check mode should not write, got %q", content)
+	}
+
+	// missing output is out of date too
+	if err := WriteOutput(filepath.Join(dir, "none.go"), []byte("package a\n"), true); err == nil {
+		t.Errorf("expect missing output out of date")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "none.go")); !os.IsNotExist(err) {
+		t.Errorf("check mode should not create output")
+	}
+}
@@ -0,0 +1,139 @@
+package errutil
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"strings"
+)
+
+// Error is a generation failure, it carries where the failure happens:
+// the generator, the source position and the offending expression
+type Error struct {
+	Generator string
+	Pos       token.Position
+	Expr      string
+	Err       error
+}
+
+func New(format string, args ...interface{}) *Error {
+	return &Error{Err: fmt.Errorf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Generator != "" {
+		b.WriteString(e.Generator)
+		b.WriteString(": ")
+	}
+	if e.Pos.IsValid() {
+		b.WriteString(e.Pos.String())
+		b.WriteString(": ")
+	}
+	if e.Err != nil {
+		b.WriteString(e.Err.Error())
+	}
+	if e.Expr != "" {
+		fmt.Fprintf(&b, " (%s)", e.Expr)
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) At(pos token.Position) *Error {
+	e.Pos = pos
+	return e
+}
+
+func (e *Error) WithExpr(expr string) *Error {
+	e.Expr = expr
+	return e
+}
+
+// WithGenerator marks err with the generator name, if it is not marked yet
+func WithGenerator(err error, name string) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		if e.Generator != "" {
+			return err
+		}
+		ne := &Error{}
+		*ne = *e
+		ne.Generator = name
+		return ne
+	}
+
+	return &Error{Generator: name, Err: err}
+}
+
+// bailout is used to unwind the deep recursive type reducing and building
+// it is always recovered by Catch at the API boundary
+type bailout struct {
+	err error
+}
+
+// Throw aborts the current generation with err
+func Throw(err error) {
+	panic(bailout{err})
+}
+
+// Throwf aborts the current generation with a formatted error
+func Throwf(format string, args ...interface{}) {
+	Throw(New(format, args...))
+}
+
+// Catch recovers the error thrown by Throw, it should be deferred:
+//
+//	func Generate() (err error) {
+//		defer errutil.Catch(&err)
+//		...
+//	}
+func Catch(errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if b, ok := r.(bailout); ok {
+		*errp = b.err
+		return
+	}
+	panic(r)
+}
+
+// CatchAt marks the errors thrown with pos, if they have no position, and throws them again,
+// it should be deferred in the scope of a declaration, e.g. a field:
+//
+//	func() {
+//		defer errutil.CatchAt(field.Position())
+//		...
+//	}()
+func CatchAt(pos token.Position) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	b, ok := r.(bailout)
+	if !ok {
+		panic(r)
+	}
+	if pos.IsValid() {
+		if e, ok := b.err.(*Error); ok {
+			if !e.Pos.IsValid() {
+				ne := &Error{}
+				*ne = *e
+				ne.Pos = pos
+				b.err = ne
+			}
+		} else {
+			b.err = &Error{Pos: pos, Err: b.err}
+		}
+	}
+	panic(b)
+}
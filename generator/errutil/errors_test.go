@@ -0,0 +1,89 @@
+package errutil
+
+import (
+	"errors"
+	"go/token"
+	"io"
+	"testing"
+)
+
+func TestErrorString(t *testing.T) {
+	pos := token.Position{Filename: "a.go", Line: 3, Column: 8}
+	err := WithGenerator(New("cannot reduce type").At(pos).WithExpr("foo.Bar"), "checker")
+
+	expect := "checker: a.go:3:8: cannot reduce type (foo.Bar)"
+	if err.Error() != expect {
+		t.Errorf("expect %q, got %q", expect, err.Error())
+	}
+
+	// marked only once
+	err = WithGenerator(err, "convert")
+	if err.Error() != expect {
+		t.Errorf("expect %q, got %q", expect, err.Error())
+	}
+}
+
+func TestCatch(t *testing.T) {
+	run := func() (err error) {
+		defer Catch(&err)
+		Throwf("import failed: %w", io.EOF)
+		return nil
+	}
+
+	err := run()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("expect io.EOF wrapped, got %v", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Errorf("expect *Error, got %T", err)
+	}
+}
+
+func TestCatchRepanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "other" {
+			t.Errorf("expect other panic, got %v", r)
+		}
+	}()
+
+	func() {
+		var err error
+		defer Catch(&err)
+		panic("other")
+	}()
+}
+
+func TestCatchAt(t *testing.T) {
+	pos := token.Position{Filename: "a.go", Line: 3, Column: 2}
+	run := func(throw func()) (err error) {
+		defer Catch(&err)
+		func() {
+			defer CatchAt(pos)
+			throw()
+		}()
+		return nil
+	}
+
+	err := run(func() { Throwf("bad rule") })
+	if err.Error() != "a.go:3:2: bad rule" {
+		t.Errorf("expect the position, got %q", err)
+	}
+
+	// the position of the error is kept
+	other := token.Position{Filename: "b.go", Line: 1, Column: 1}
+	err = run(func() { Throw(New("bad type").At(other)) })
+	if err.Error() != "b.go:1:1: bad type" {
+		t.Errorf("expect the own position, got %q", err)
+	}
+
+	err = run(func() { Throw(io.EOF) })
+	if !errors.Is(err, io.EOF) || err.Error() != "a.go:3:2: EOF" {
+		t.Errorf("expect io.EOF at the position, got %q", err)
+	}
+
+	if err := run(func() {}); err != nil {
+		t.Errorf("expect nil, got %v", err)
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
+	"go/printer"
 	"go/token"
-	"io/ioutil"
 	"log"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/parser"
 	"golang.org/x/tools/imports"
 )
@@ -21,7 +24,21 @@ type Generator struct {
 	File *parser.File
 	Pkg  *parser.Package
 
+	// Header is written on top of the output, see CodeHeader
+	Header string
+	// Check only compares the result with the output, see WriteOutput
+	Check bool
+	// Verify type checks the result before it is written, see VerifyOutput
+	Verify bool
+	// Outputs keeps the result in memory if not nil, nothing is written
+	Outputs *Outputs
+
 	workFile *parser.File
+	owners   owners
+	// comments of the declarations added by AddCode
+	comments []*ast.CommentGroup
+	// parsed are the declarations read from the output or added by AddCode, they keep their layout
+	parsed map[ast.Decl]bool
 }
 
 func (g *Generator) WorkFile() *parser.File {
@@ -42,29 +59,79 @@ func (g *Generator) ReduceType(expr ast.Expr) parser.Type {
 }
 func (g *Generator) ReduceTypeSrc(src string) parser.Type {
 	expr, err := parser.ParseExpr(src)
-	if err == nil {
-		return g.ReduceType(expr)
+	if err != nil {
+		errutil.Throw(errutil.New("parse expr failed: %w", err).WithExpr(src))
 	}
-	log.Fatalf("expr error: %s:%v", src, err)
-	return nil
+	return g.ReduceType(expr)
 }
 
 // Bytes return the generated bytes
-func (g *Generator) Bytes() []byte {
+// the declarations are written one by one, with the owner marker, see Own
+func (g *Generator) Bytes() (result []byte, err error) {
+	defer errutil.Catch(&err)
+
+	g.removeStale()
+
+	header := g.Header
+	if header == "" {
+		header = CodeHeader("", "")
+	}
+	if g.sharedOutput() {
+		header = sharedHeader(header)
+	}
 
 	var buf bytes.Buffer
-	// fmt.Fprintf(&buf, "// Code generated by \"%s\"; DO NOT EDIT.\n", strings.Join(os.Args[0:], " "))
-	if err := format.Node(&buf, token.NewFileSet(), g.File.File); err != nil {
-		log.Fatalf("generate code error: %v", err)
+	fmt.Fprintf(&buf, "%s\n", header)
+	fmt.Fprintf(&buf, "package %s\n", g.File.File.Name.Name)
+
+	comments := append(append([]*ast.CommentGroup{}, g.File.File.Comments...), g.comments...)
+	for _, decl := range g.File.File.Decls {
+		// the declarations read or added as code keep their layout and comments,
+		// the generated ones have the positions of the parsed expressions, which are not in the output
+		fset := token.NewFileSet()
+		var declComments []*ast.CommentGroup
+		if g.parsed[decl] && g.Parser != nil {
+			fset = g.Parser.FileSet
+			declComments = commentsIn(comments, decl)
+		}
+		buf.WriteString("\n")
+		for _, c := range declDoc(decl) {
+			if !strings.HasPrefix(c.Text, OwnerPrefix) {
+				fmt.Fprintf(&buf, "%s\n", c.Text)
+			}
+		}
+		if owner := g.OwnerOf(decl); owner != "" {
+			fmt.Fprintf(&buf, "%s%s\n", OwnerPrefix, owner)
+		}
+		node := &printer.CommentedNode{Node: withoutDoc(decl), Comments: declComments}
+		if err := format.Node(&buf, fset, node); err != nil {
+			return nil, fmt.Errorf("generate code error: %w", err)
+		}
+		buf.WriteString("\n")
 	}
 
-	return buf.Bytes()
+	return buf.Bytes(), nil
+}
+
+// commentsIn returns the comments inside the declaration, the doc is not included,
+// the generated declarations may have the positions of other files, they have no comments
+func commentsIn(comments []*ast.CommentGroup, decl ast.Decl) []*ast.CommentGroup {
+	if !decl.Pos().IsValid() {
+		return nil
+	}
+	result := []*ast.CommentGroup{}
+	for _, c := range comments {
+		if c.Pos() >= decl.Pos() && c.End() <= decl.End() {
+			result = append(result, c)
+		}
+	}
+	return result
 }
 
 func (g *Generator) GetExprString(expr ast.Expr) string {
 	var buf bytes.Buffer
 	if err := format.Node(&buf, token.NewFileSet(), expr); err != nil {
-		log.Fatalf("generate code error: %v", err)
+		errutil.Throwf("generate code error: %w", err)
 	}
 
 	return buf.String()
@@ -96,7 +163,11 @@ func (g *Generator) Format() ([]byte, error) {
 		TabIndent: true,
 		Comments:  true,
 	}
-	res, err := imports.Process("", g.Bytes(), options)
+	src, err := g.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	res, err := imports.Process("", src, options)
 	// src, err := format.Source(g.buf.Bytes())
 	if err != nil {
 		// Should never happen, but can arise when developing this code.
@@ -108,12 +179,18 @@ func (g *Generator) Format() ([]byte, error) {
 	return res, nil
 }
 
+// PrepareParser creates the parser, unless a shared one is given
 func (g *Generator) PrepareParser() {
-	g.Parser = parser.NewParser()
+	if g.Parser == nil {
+		g.Parser = parser.NewParser()
+	}
 }
-func (g *Generator) PreparePackage(dir string, output string) {
+func (g *Generator) PreparePackage(dir string, output string) error {
 	p := g.Parser
-	pkg := p.ParsePackageDir(dir)
+	pkg, err := p.ParsePackageDir(dir)
+	if err != nil {
+		return err
+	}
 
 	var file *parser.File
 
@@ -126,30 +203,47 @@ func (g *Generator) PreparePackage(dir string, output string) {
 		fmt.Fprintf(&buf, "package %v\n", pkg.Name)
 		// add custom imports
 		fmt.Fprintf(&buf, "import ()\n")
-		file = p.ParseFileContent("_prepare", buf.Bytes())
+		file, err = p.ParseFileContent("_prepare", buf.Bytes())
+		if err != nil {
+			return err
+		}
 		p.InsertFileToPackage(pkg, file, 0)
 	}
 
-	g.File = file
+	g.setFile(file)
 	g.Pkg = pkg
+	return nil
 }
 
-func (g *Generator) Prepare(dir string, files []string, output string) {
+// setFile sets the output file, its declarations keep their layout, see Bytes
+func (g *Generator) setFile(file *parser.File) {
+	g.File = file
+	g.parsed = declSet(file.File.Decls)
+}
+
+func (g *Generator) Prepare(dir string, files []string, output string) error {
 	g.PrepareParser()
 
 	p := g.Parser
 	var pkg *parser.Package
+	var err error
 
 	if len(files) == 0 {
-		pkg = p.ParsePackageDir(dir)
+		if dir == "" {
+			dir = "."
+		}
+		pkg, err = p.ParsePackageDir(dir)
 	} else {
 		if output != "" {
-			if ok, err := PathExists(output); err == nil && ok {
+			if ok, err := g.Outputs.exists(output); err == nil && ok {
 				files = append(files, output)
 			}
 		}
 
-		pkg = p.ParsePackageFiles(files)
+		pkg, err = p.ParsePackageFiles(files)
+	}
+	if err != nil {
+		return err
 	}
 	var file *parser.File
 
@@ -163,22 +257,38 @@ func (g *Generator) Prepare(dir string, files []string, output string) {
 		fmt.Fprintf(&buf, "package %v\n", pkg.Name)
 		// add custom imports
 		fmt.Fprintf(&buf, "import ()\n")
-		file = p.ParseFileContent("_prepare", buf.Bytes())
+		file, err = p.ParseFileContent("_prepare", buf.Bytes())
+		if err != nil {
+			return err
+		}
 		p.InsertFileToPackage(pkg, file, 0)
 	}
 
 	g.workFile = nil
-	g.File = file
+	g.setFile(file)
 	g.Pkg = pkg
+	return nil
 }
 
-func (g *Generator) PrepareImports(imports map[string]string) {
+func (g *Generator) PrepareImports(imports map[string]string) (err error) {
+	defer errutil.Catch(&err)
+
 	bd := builder.NewFile(nil, g.File)
-	if len(imports) > 0 {
-		for name, path := range imports {
-			bd.AddImport(name, path)
-		}
+	for _, name := range SortedKeys(imports) {
+		bd.AddImport(name, imports[name])
 	}
+	return nil
+}
+
+// SortedKeys returns the keys of the map in order,
+// so the generators range over maps the same way every time
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func PathExists(path string) (bool, error) {
@@ -192,7 +302,7 @@ func PathExists(path string) (bool, error) {
 	return false, err
 }
 
-func (g *Generator) PrepareWithFile(fileName string, output string) {
+func (g *Generator) PrepareWithFile(fileName string, output string) error {
 	g.PrepareParser()
 	p := g.Parser
 
@@ -202,16 +312,19 @@ func (g *Generator) PrepareWithFile(fileName string, output string) {
 	outputExists := false
 
 	if output != "" {
-		exists, err := PathExists(output)
+		exists, err := g.Outputs.exists(output)
 		if err != nil {
-			log.Fatalf("check output(%s) exists failed %s", output, err)
+			return fmt.Errorf("check output(%s) exists failed: %w", output, err)
 		}
 		if exists {
 			names = append(names, output)
 			outputExists = true
 		}
 	}
-	pkg = p.ParsePackageFiles(names)
+	pkg, err := p.ParsePackageFiles(names)
+	if err != nil {
+		return err
+	}
 
 	var file *parser.File
 	if outputExists {
@@ -223,31 +336,36 @@ func (g *Generator) PrepareWithFile(fileName string, output string) {
 		fmt.Fprintf(&buf, "package %v\n", pkg.Name)
 		// add custom imports
 		fmt.Fprintf(&buf, "import ()\n")
-		file = p.ParseFileContent("_prepare", buf.Bytes())
+		file, err = p.ParseFileContent("_prepare", buf.Bytes())
+		if err != nil {
+			return err
+		}
 		p.InsertFileToPackage(pkg, file, 0)
 	}
 
 	g.workFile = pkg.GetFile(fileName)
-	g.File = file
+	g.setFile(file)
 	g.Pkg = pkg
 
+	return nil
 }
 
-func (g *Generator) Output(output string) {
+func (g *Generator) Output(output string) error {
 	// fmt.Printf("%s: %s", output, string(g.Bytes()))
 	// Format the output.
 	result, err := g.Format()
 	if err != nil {
-		log.Fatalf("Foramt Failed: %v", err)
+		return fmt.Errorf("format failed: %w", err)
 	}
 
-	// Write to stdout / file
-	if len(output) == 0 {
-		fmt.Println(string(result))
-	} else {
-		err = ioutil.WriteFile(output, result, 0644)
-		if err != nil {
-			log.Fatalf("writing output: %s", err)
+	if g.Verify {
+		if err := VerifyOutput(g.Parser, output, result); err != nil {
+			return err
 		}
 	}
+	if g.Outputs != nil {
+		return g.Outputs.Write(g.Parser, output, result)
+	}
+	// Write to stdout / file
+	return WriteOutput(output, result, g.Check)
 }
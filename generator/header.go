@@ -0,0 +1,41 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// the canonical header, see https://golang.org/s/generatedcode
+var generatedRegexp = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// CodeHeader returns the generated code header line, like:
+//
+//	// Code generated by "pigo checker --type=A"; DO NOT EDIT.
+//	// pigo version: 1.0.6
+//
+// the result only depends on the arguments, so it is stable between runs
+func CodeHeader(version string, command string, args ...string) string {
+	cmd := append([]string{"pigo"}, command)
+	cmd = append(cmd, args...)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by \"%s\"; DO NOT EDIT.\n", strings.TrimSpace(strings.Join(cmd, " ")))
+	if version != "" {
+		fmt.Fprintf(&b, "// pigo version: %s\n", version)
+	}
+	return b.String()
+}
+
+// IsGeneratedHeader reports whether the comment line is a generated code header
+func IsGeneratedHeader(line string) bool {
+	return generatedRegexp.MatchString(line)
+}
+
+// sharedHeader is the header of an output shared by the commands,
+// no command is recorded, the other lines are kept
+func sharedHeader(header string) string {
+	lines := strings.SplitN(header, "\n", 2)
+	lines[0] = "// Code generated by pigo; DO NOT EDIT."
+	return strings.Join(lines, "\n")
+}
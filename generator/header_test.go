@@ -0,0 +1,49 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+func TestHeaderReplaced(t *testing.T) {
+	header := CodeHeader("1.0.6", "checker", "--type=A")
+
+	code := header + `
+package fake
+
+// A is kept
+type A struct{}
+`
+	p := parser.NewParser()
+	file, err := p.ParseFileContent("fake.go", code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Parser: p, File: file, Header: header}
+	result, err := g.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := bytes.Count(result, []byte("// Code generated")); n != 1 {
+		t.Errorf("expect 1 header, got %d:\n%s", n, result)
+	}
+	if !bytes.HasPrefix(result, []byte(header)) {
+		t.Errorf("expect header on top, got:\n%s", result)
+	}
+	if !bytes.Contains(result, []byte("// A is kept")) {
+		t.Errorf("expect doc comment kept, got:\n%s", result)
+	}
+}
+
+func TestIsGeneratedHeader(t *testing.T) {
+	if !IsGeneratedHeader(`// Code generated by "pigo checker --type=A"; DO NOT EDIT.`) {
+		t.Errorf("expect header matched")
+	}
+	if IsGeneratedHeader("// Code generated by hand") {
+		t.Errorf("expect header not matched")
+	}
+}
@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// DriftError is returned in check mode when the output is out of date
+type DriftError struct {
+	Output string
+	Diff   string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("%s is out of date:\n%s", e.Output, e.Diff)
+}
+
+// Outputs keeps the generated results in memory by output, instead of writing the files,
+// they are overlaid on the parser, so the next generators see them as written
+// the zero value is empty, it is safe for the generators running at once
+type Outputs struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+// Write keeps the result of output
+func (o *Outputs) Write(p *parser.Parser, output string, result []byte) error {
+	if len(output) == 0 {
+		return errors.New("in memory output requires an output file")
+	}
+	o.mu.Lock()
+	if o.files == nil {
+		o.files = map[string][]byte{}
+	}
+	o.files[output] = result
+	o.mu.Unlock()
+	return p.AddOverlay(output, result)
+}
+
+// Get returns the result kept of output, nil if there is none
+func (o *Outputs) Get(output string) []byte {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.files[output]
+}
+
+// Files returns a copy of the results by output
+func (o *Outputs) Files() map[string][]byte {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	files := make(map[string][]byte, len(o.files))
+	for output, result := range o.files {
+		files[output] = result
+	}
+	return files
+}
+
+// exists reports whether output is kept or on disk
+func (o *Outputs) exists(output string) (bool, error) {
+	if o.Get(output) != nil {
+		return true, nil
+	}
+	return PathExists(output)
+}
+
+// WriteOutput writes the generated result to output, or stdout when output is empty
+// in check mode, it only compares the result with the existing output and never writes
+func WriteOutput(output string, result []byte, check bool) error {
+	if check {
+		return CheckOutput(output, result)
+	}
+
+	if len(output) == 0 {
+		fmt.Println(string(result))
+		return nil
+	}
+	if err := ioutil.WriteFile(output, result, 0644); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+	return nil
+}
+
+// CheckOutput compares the generated result with the existing output
+// a missing output file is treated as empty
+func CheckOutput(output string, result []byte) error {
+	if len(output) == 0 {
+		return errors.New("check mode requires an output file")
+	}
+
+	old, err := ioutil.ReadFile(output)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading output: %w", err)
+	}
+
+	name := filepath.ToSlash(output)
+	if diff := UnifiedDiff("a/"+name, "b/"+name, old, result); diff != "" {
+		return &DriftError{Output: output, Diff: diff}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package generator
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator/errutil"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// OwnerPrefix marks the owner of a generated declaration, like:
+//
+//	//pigo:owner checker CreateParam Validate
+//
+// so the generators can share one output, and a rerun only replaces its own declarations
+const OwnerPrefix = "//pigo:owner "
+
+// owners tracks the owners of the declarations in the output
+type owners struct {
+	// current owner, and the declarations when it starts
+	owner  string
+	before map[ast.Decl]bool
+
+	// the declarations read from the existing output
+	loaded map[ast.Decl]bool
+	// the owners of the declarations added in this run
+	added   map[ast.Decl]string
+	claimed map[string]bool
+}
+
+// Own starts the declarations of owner, the ones added until the next Own are marked with it,
+// the owner is the command, the type and the generated function, e.g. "checker CreateParam Validate",
+// so the runs for the same type with different names share the output
+// when the output is written, the declarations it owned before but not added again are removed
+func (g *Generator) Own(owner string) {
+	o := &g.owners
+	if o.loaded == nil {
+		o.loaded = declSet(g.File.File.Decls)
+		o.added = map[ast.Decl]string{}
+		o.claimed = map[string]bool{}
+	}
+	g.markOwned()
+
+	o.owner = owner
+	o.before = declSet(g.File.File.Decls)
+	o.claimed[owner] = true
+}
+
+// markOwned marks the declarations added by the current owner
+func (g *Generator) markOwned() {
+	o := &g.owners
+	if o.owner == "" {
+		return
+	}
+
+	current := declSet(g.File.File.Decls)
+	for decl := range o.before {
+		// replaced by the current owner
+		if owner := g.OwnerOf(decl); !current[decl] && owner != "" && owner != o.owner && !legacyOwner(owner, o.owner) {
+			errutil.Throwf("%s is generated by %q, cannot be replaced by %q", declName(decl), owner, o.owner)
+		}
+	}
+	for _, decl := range g.File.File.Decls {
+		if !o.before[decl] {
+			o.added[decl] = o.owner
+		}
+	}
+
+	o.owner = ""
+	o.before = nil
+}
+
+// removeStale removes the loaded declarations of the owners in this run, which are not added again
+func (g *Generator) removeStale() {
+	g.markOwned()
+
+	o := &g.owners
+	if o.loaded == nil {
+		return
+	}
+
+	added := map[string]bool{}
+	for decl := range o.added {
+		added[declName(decl)] = true
+	}
+
+	decls := g.File.File.Decls[:0]
+	for _, decl := range g.File.File.Decls {
+		if o.loaded[decl] && g.stale(decl, added) {
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	g.File.File.Decls = decls
+}
+
+// stale reports whether the loaded declaration is replaced by this run,
+// the owners without the function name, written by the former versions, are replaced by the added declarations of the same name
+func (g *Generator) stale(decl ast.Decl, added map[string]bool) bool {
+	owner := g.OwnerOf(decl)
+	if owner == "" {
+		return false
+	}
+	if g.owners.claimed[owner] {
+		return true
+	}
+	if !added[declName(decl)] {
+		return false
+	}
+	for claimed := range g.owners.claimed {
+		if legacyOwner(owner, claimed) {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyOwner reports whether owner is the former owner of the claimed one, which has no function name
+func legacyOwner(owner string, claimed string) bool {
+	return strings.HasPrefix(claimed, owner+" ")
+}
+
+// OwnerOf returns the owner of the declaration, or "" if it has none
+func (g *Generator) OwnerOf(decl ast.Decl) string {
+	if owner, ok := g.owners.added[decl]; ok {
+		return owner
+	}
+	for _, c := range declDoc(decl) {
+		if strings.HasPrefix(c.Text, OwnerPrefix) {
+			return strings.TrimSpace(c.Text[len(OwnerPrefix):])
+		}
+	}
+	return ""
+}
+
+// sharedOutput reports whether the declarations are owned by more than one command
+func (g *Generator) sharedOutput() bool {
+	command := ""
+	for _, decl := range g.File.File.Decls {
+		owner := g.OwnerOf(decl)
+		if owner == "" {
+			continue
+		}
+		cmd := strings.Fields(owner)[0]
+		if command != "" && cmd != command {
+			return true
+		}
+		command = cmd
+	}
+	return false
+}
+
+func declSet(decls []ast.Decl) map[ast.Decl]bool {
+	set := make(map[ast.Decl]bool, len(decls))
+	for _, decl := range decls {
+		set[decl] = true
+	}
+	return set
+}
+
+func declDoc(decl ast.Decl) []*ast.Comment {
+	var doc *ast.CommentGroup
+	switch x := decl.(type) {
+	case *ast.FuncDecl:
+		doc = x.Doc
+	case *ast.GenDecl:
+		doc = x.Doc
+	}
+	if doc == nil {
+		return nil
+	}
+	return doc.List
+}
+
+// withoutDoc returns a copy of the declaration without doc, the doc is written by Bytes
+func withoutDoc(decl ast.Decl) ast.Decl {
+	switch x := decl.(type) {
+	case *ast.FuncDecl:
+		d := *x
+		d.Doc = nil
+		return &d
+	case *ast.GenDecl:
+		d := *x
+		d.Doc = nil
+		return &d
+	}
+	return decl
+}
+
+func declName(decl ast.Decl) string {
+	switch x := decl.(type) {
+	case *ast.FuncDecl:
+		if x.Recv != nil && len(x.Recv.List) > 0 {
+			return "method " + parser.ExprToString(x.Recv.List[0].Type) + "." + x.Name.Name
+		}
+		return "func " + x.Name.Name
+	case *ast.GenDecl:
+		names := []string{}
+		for _, spec := range x.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		return strings.TrimSpace(x.Tok.String() + " " + strings.Join(names, ", "))
+	}
+	return "declaration"
+}
@@ -0,0 +1,179 @@
+package generator
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/builder"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+func addFunc(g *Generator, name string, src string) {
+	file := builder.NewFile(nil, g.File)
+	bd := builder.NewFuncBuffer(file, name)
+	bd.Printf("%s", src)
+	file.Add(bd)
+}
+
+func TestOwn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "zz_generated.go")
+	files := map[string]string{
+		"a.go": "package a\n\ntype A int\n",
+		"zz_generated.go": `package a
+
+//pigo:owner x A
+func X1() {}
+
+//pigo:owner y A
+func Y() {}
+
+//pigo:owner x A
+func X2() {}
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Header: CodeHeader("", "x")}
+	if err := g.Prepare(dir, nil, output); err != nil {
+		t.Fatal(err)
+	}
+	g.Own("x A")
+	addFunc(g, "X2", "func X2() { println(2) }\n")
+	addFunc(g, "X3", "func X3() {}\n")
+
+	result, err := g.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `// Code generated by pigo; DO NOT EDIT.
+
+package a
+
+//pigo:owner y A
+func Y() {}
+
+//pigo:owner x A
+func X2() {
+	println(2)
+}
+
+//pigo:owner x A
+func X3() {
+}
+`
+	if got := string(result); got != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, got)
+	}
+}
+
+// the generated declarations have the positions of the parsed expressions, which are not of the output,
+// they are written as without positions, whatever the files parsed before
+func TestBytesGenerated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\n\n\n\n\n\n\n\ntype A int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{}
+	if err := g.Prepare(dir, nil, filepath.Join(dir, "zz_generated.go")); err != nil {
+		t.Fatal(err)
+	}
+	addFunc(g, "F", "func F() {\n\tx := 0\n\t_ = x\n}\n")
+	call, err := parser.ParseExpr("fmt.Sprint(1, 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := g.File.File.Decls
+	decls[len(decls)-1].(*ast.FuncDecl).Body.List[0].(*ast.AssignStmt).Rhs[0] = call
+
+	result, err := g.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "func F() {\n\tx := fmt.Sprint(1, 2)\n\t_ = x\n}\n"
+	if !strings.Contains(string(result), expect) {
+		t.Errorf("expect %q in:\n%s", expect, result)
+	}
+}
+
+// the owners without the function name are replaced by the declarations of the same name
+func TestOwnLegacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "zz_generated.go")
+	files := map[string]string{
+		"a.go":            "package a\n\ntype A int\n",
+		"zz_generated.go": "package a\n\n//pigo:owner x A\nfunc X1() {}\n\n//pigo:owner x A\nfunc X2() {}\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Header: CodeHeader("", "x")}
+	if err := g.Prepare(dir, nil, output); err != nil {
+		t.Fatal(err)
+	}
+	g.Own("x A X2")
+	addFunc(g, "X2", "func X2() { println(2) }\n")
+
+	result, err := g.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := `// Code generated by "pigo x"; DO NOT EDIT.
+
+package a
+
+//pigo:owner x A
+func X1() {}
+
+//pigo:owner x A X2
+func X2() {
+	println(2)
+}
+`
+	if got := string(result); got != expect {
+		t.Errorf("expect:\n%s\ngot:\n%s", expect, got)
+	}
+}
+
+func TestOwnConflict(t *testing.T) {
+	g := &Generator{}
+	if err := g.Prepare("", []string{"header.go"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	g.Own("x A")
+	addFunc(g, "F", "func F() {}\n")
+	g.Own("y A")
+	addFunc(g, "F", "func F() {}\n")
+
+	if _, err := g.Bytes(); err == nil || !strings.Contains(err.Error(), `func F is generated by "x A"`) {
+		t.Errorf("expect conflict error, got %v", err)
+	}
+}
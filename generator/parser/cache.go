@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// the version of the entries, it is changed when the format or the parsing is changed
+const cacheVersion = "pigo-cache-1"
+
+// DiskCache keeps the packages imported by the BuildLoader on disk, so the next runs do not look them up again,
+// which runs the go command in module mode, and parse the declarations of their files only,
+// the declarations of a file are kept by the hash of its contents and the version of go,
+// the bodies of the functions are dropped, the types are resolved from the declarations only,
+// the files found by an import are kept by the build context and the go.mod, go.sum and go.work of the main module,
+// and they are found again when a go file in the directory is added, removed or changed,
+// the errors of the cache are ignored, the files are parsed then
+type DiskCache struct {
+	Dir string
+}
+
+// NewDiskCache returns the cache in the pigo directory of the user cache directory
+func NewDiskCache() (*DiskCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &DiskCache{Dir: filepath.Join(dir, "pigo")}, nil
+}
+
+// cacheKey hashes the parts with the version of the cache and go
+func cacheKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{cacheVersion, runtime.Version()}, parts...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *DiskCache) path(kind string, key string) string {
+	return filepath.Join(c.Dir, kind, key[:2], key)
+}
+
+// put writes the entry by renaming, so a reader never sees it partly written
+func (c *DiskCache) put(kind string, key string, content []byte) {
+	name := c.path(kind, key)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *DiskCache) get(kind string, key string) ([]byte, bool) {
+	content, err := ioutil.ReadFile(c.path(kind, key))
+	return content, err == nil
+}
+
+// parseDecls parses the declarations of the file, from the cache if its contents are not changed
+func (c *DiskCache) parseDecls(fset *token.FileSet, name string) (*ast.File, error) {
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	key := cacheKey("decls", string(content))
+	decls, ok := c.get("decls", key)
+	if !ok {
+		if decls, err = stripBodies(name, content); err != nil {
+			return nil, err
+		}
+		c.put("decls", key, decls)
+	}
+	return parser.ParseFile(fset, name, decls, parser.ParseComments)
+}
+
+// stripBodies replaces the bodies of the functions by spaces, the lines and columns of the declarations are kept
+func stripBodies(name string, content []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, content, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	stripped := append([]byte{}, content...)
+	base := fset.File(file.Package).Base()
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		for i := int(fn.Body.Lbrace) - base + 1; i < int(fn.Body.Rbrace)-base; i++ {
+			if stripped[i] != '\n' {
+				stripped[i] = ' '
+			}
+		}
+	}
+	return stripped, nil
+}
+
+// importEntry is an import found, the stamps are of the go files in Dir, by name
+type importEntry struct {
+	Path    string
+	Dir     string
+	GoFiles []string
+	Stamps  map[string]fileStamp
+}
+
+type fileStamp struct {
+	ModTime int64
+	Size    int64
+}
+
+// importKey is the key of the import in the build context, empty if it should not be cached,
+// the main module is found in the working directory by go/build, see build.Context.Dir
+func importKey(ctx *build.Context, path string, srcDir string) string {
+	abs, err := filepath.Abs(srcDir)
+	if err != nil {
+		return ""
+	}
+	wd := ctx.Dir
+	if wd == "" {
+		if wd, err = os.Getwd(); err != nil {
+			return ""
+		}
+	}
+	if wd, err = filepath.Abs(wd); err != nil {
+		return ""
+	}
+	parts := []string{
+		"import", path, abs, wd,
+		ctx.GOOS, ctx.GOARCH, ctx.GOROOT, ctx.GOPATH, ctx.Compiler,
+		strings.Join(ctx.BuildTags, ","), strings.Join(ctx.ToolTags, ","), strings.Join(ctx.ReleaseTags, ","),
+		boolString(ctx.CgoEnabled),
+		os.Getenv("GO111MODULE"), os.Getenv("GOFLAGS"), os.Getenv("GOWORK"),
+	}
+	return cacheKey(append(parts, moduleFiles(wd)...)...)
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// moduleFiles returns the names and the contents of the files selecting the modules of the main module in dir
+func moduleFiles(dir string) []string {
+	parts := []string{}
+	add := func(names ...string) {
+		for _, name := range names {
+			content, err := ioutil.ReadFile(name)
+			if err != nil {
+				continue
+			}
+			parts = append(parts, name, string(content))
+		}
+	}
+
+	for d, mod := dir, false; ; {
+		if !mod {
+			if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+				mod = true
+				add(filepath.Join(d, "go.mod"), filepath.Join(d, "go.sum"), filepath.Join(d, "vendor", "modules.txt"))
+			}
+		}
+		add(filepath.Join(d, "go.work"), filepath.Join(d, "go.work.sum"))
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	if work := os.Getenv("GOWORK"); work != "" && work != "off" {
+		add(work, work+".sum")
+	}
+	return parts
+}
+
+// goStamps returns the stamps of the go files in the directory
+func goStamps(dir string) (map[string]fileStamp, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	stamps := map[string]fileStamp{}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") {
+			continue
+		}
+		stamps[info.Name()] = fileStamp{ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, stamp := range a {
+		if b[name] != stamp {
+			return false
+		}
+	}
+	return true
+}
+
+// getImport returns the import found before, if the go files of its directory are not changed
+func (c *DiskCache) getImport(key string) (*importEntry, bool) {
+	content, ok := c.get("imports", key)
+	if !ok {
+		return nil, false
+	}
+	entry := &importEntry{}
+	if err := json.Unmarshal(content, entry); err != nil {
+		return nil, false
+	}
+	stamps, err := goStamps(entry.Dir)
+	if err != nil || !sameStamps(stamps, entry.Stamps) {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *DiskCache) putImport(key string, entry *importEntry) {
+	stamps, err := goStamps(entry.Dir)
+	if err != nil {
+		return
+	}
+	entry.Stamps = stamps
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.put("imports", key, content)
+}
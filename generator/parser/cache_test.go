@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"go/ast"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"go.mod": "module example.com/a\n\ngo 1.18\n",
+		"b/b.go": "package b\n\nfunc F() int {\n\treturn 1\n}\n\ntype T int\n",
+	})
+	cache := &DiskCache{Dir: filepath.Join(dir, "cache")}
+	b := filepath.Join(dir, "b", "b.go")
+
+	importB := func() *Package {
+		p := NewParser()
+		p.DiskCache = cache
+		p.ImportContext.Dir = dir
+		pkg, err := p.ImportPackage("b", "example.com/a/b", dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	names := func(pkg *Package) []string {
+		result := []string{}
+		for _, file := range pkg.Files {
+			for _, obj := range file.File.Scope.Objects {
+				result = append(result, obj.Name)
+			}
+		}
+		return result
+	}
+
+	// the bodies are dropped, the lines of the declarations are kept
+	pkg := importB()
+	file := pkg.GetFile(b)
+	fn := file.File.Decls[0].(*ast.FuncDecl)
+	expect(t.Errorf, len(fn.Body.List), 0)
+	spec := file.File.Decls[1].(*ast.GenDecl).Specs[0].(*ast.TypeSpec)
+	expect(t.Errorf, pkg.parser.Position(spec.Pos()).Line, 7)
+
+	// the declarations are read from the cache while the contents are not changed
+	content, err := ioutil.ReadFile(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.put("decls", cacheKey("decls", string(content)), []byte("package b\n\ntype Cached int\n"))
+	expect(t.Errorf, names(importB()), []string{"Cached"})
+
+	// a changed file is parsed again
+	writeFiles(t, dir, map[string]string{"b/b.go": "package b\n\ntype T string\n"})
+	expect(t.Errorf, names(importB()), []string{"T"})
+
+	// an added file is found
+	writeFiles(t, dir, map[string]string{"b/c.go": "package b\n\ntype C int\n"})
+	expect(t.Errorf, len(importB().Files), 2)
+
+	// the imports are found again when the dependencies are changed
+	ctx := NewParser().ImportContext
+	ctx.Dir = dir
+	key := importKey(&ctx, "example.com/a/b", dir)
+	writeFiles(t, dir, map[string]string{"go.mod": "module example.com/a\n\ngo 1.18\n\nrequire example.com/c v1.0.0\n"})
+	assert(t, importKey(&ctx, "example.com/a/b", dir) != key, "the key should be changed with go.mod")
+}
+
+// the imports of the standard library are found by go/build, so the cache saves the parsing of the bodies,
+// the ones of the modules are found by the go command, which the cache saves too
+func BenchmarkDiskCache(b *testing.B) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, path := range []string{"net/http", "golang.org/x/tools/go/packages"} {
+		importPackage := func(b *testing.B, cache *DiskCache) {
+			p := NewParser()
+			p.DiskCache = cache
+			if _, err := p.ImportPackage("x", path, "."); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.Run(path+"/parse", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				importPackage(b, nil)
+			}
+		})
+		b.Run(path+"/cached", func(b *testing.B) {
+			cache := &DiskCache{Dir: filepath.Join(dir, filepath.Base(path))}
+			importPackage(b, cache)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				importPackage(b, cache)
+			}
+		})
+	}
+}
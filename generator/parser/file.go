@@ -2,8 +2,11 @@ package parser
 
 import (
 	"go/ast"
+	"go/token"
 	"strconv"
 	"strings"
+
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 type File struct {
@@ -16,6 +19,47 @@ type File struct {
 
 	dotImports  []*Package
 	nameImports map[string]*Package
+
+	// the type parameters in scope, bound to the type arguments
+	typeArgs map[string]Type
+}
+
+// withTypeArgs returns a copy of the file, in which the names of type parameters are bound to args
+func (f *File) withTypeArgs(names []string, args []Type) *File {
+	nf := *f
+	nf.typeArgs = make(map[string]Type, len(f.typeArgs)+len(names))
+	for name, t := range f.typeArgs {
+		nf.typeArgs[name] = t
+	}
+	for i, name := range names {
+		nf.typeArgs[name] = args[i]
+	}
+	return &nf
+}
+
+// errorf makes an error located at node
+func (f *File) errorf(node ast.Node, format string, args ...interface{}) *errutil.Error {
+	e := errutil.New(format, args...)
+	if node == nil {
+		return e
+	}
+	if expr, ok := node.(ast.Expr); ok {
+		e.WithExpr(ExprToString(expr))
+	}
+	if f.contains(node.Pos()) {
+		e.At(f.parser.Position(node.Pos()))
+	}
+	return e
+}
+
+// contains reports whether pos is in the file,
+// the expressions from ParseExpr have positions of their own
+func (f *File) contains(pos token.Pos) bool {
+	if f == nil || f.parser == nil || f.File == nil || !pos.IsValid() {
+		return false
+	}
+	tf := f.parser.FileSet.File(f.File.Package)
+	return tf != nil && tf.Base() <= int(pos) && int(pos) <= tf.Base()+tf.Size()
 }
 
 func nameOfImport(ispc *ast.ImportSpec) string {
@@ -152,7 +196,10 @@ func (file *File) FindDecl(expr ast.Expr, finder DeclFinder) *DeclNode {
 			// not in this file
 			return nil
 		}
-		pkg := file.parser.ImportPackage(pkgName, path, file.BelongTo.Dir)
+		pkg, err := file.parser.ImportPackage(pkgName, path, file.BelongTo.Dir)
+		if err != nil {
+			errutil.Throw(file.errorf(expr, "%w", err))
+		}
 		// _ = p.ImportPackageByName(srcPkg, pkgName)
 		file.AddImport(pkgName, pkg)
 
@@ -205,7 +252,10 @@ func (f *File) ImportAllDots() []*Package {
 	pkgs := []*Package{}
 	if paths := f.FindAllDotImport(); len(paths) > 0 {
 		for _, path := range paths {
-			pkg := f.parser.ImportPackage(f.BelongTo.Name, ".", path)
+			pkg, err := f.parser.ImportPackage(f.BelongTo.Name, ".", path)
+			if err != nil {
+				errutil.Throw(err)
+			}
 			pkgs = append(pkgs, pkg)
 			f.AddImport(".", pkg)
 		}
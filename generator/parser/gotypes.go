@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// typeObject returns the go/types object of the type spec, if the package is type checked
+func (file *File) typeObject(spec *ast.TypeSpec) *types.TypeName {
+	if file.BelongTo == nil || file.BelongTo.TypesInfo == nil {
+		return nil
+	}
+	obj, _ := file.BelongTo.TypesInfo.Defs[spec.Name].(*types.TypeName)
+	return obj
+}
+
+// GoType returns the go/types type of t, or nil if some part of it is not type checked
+func GoType(t Type) types.Type {
+	switch x := t.(type) {
+	case *BasicType:
+		switch x.name {
+		case "interface":
+			return types.NewInterfaceType(nil, nil).Complete()
+		case "float":
+			return nil
+		}
+		if obj, ok := types.Universe.Lookup(x.name).(*types.TypeName); ok {
+			return obj.Type()
+		}
+	case *namedType:
+		if x.obj == nil {
+			return nil
+		}
+		if x.args == nil {
+			return x.obj.Type()
+		}
+		targs := make([]types.Type, len(x.args))
+		for i, arg := range x.args {
+			if targs[i] = GoType(arg); targs[i] == nil {
+				return nil
+			}
+		}
+		inst, err := types.Instantiate(nil, x.obj.Type(), targs, false)
+		if err != nil {
+			return nil
+		}
+		return inst
+	case *aliasType:
+		if x.alias != nil {
+			return GoType(x.alias)
+		}
+	case *filedType:
+		return GoType(x.Type)
+	case *exprType:
+		return GoType(x.Type)
+	case *Field:
+		return GoType(x.Type)
+	case *PointerType:
+		base := GoType(x.Base)
+		if base == nil {
+			return nil
+		}
+		for i := 0; i < x.Stars; i++ {
+			base = types.NewPointer(base)
+		}
+		return base
+	case *ArrayType:
+		elem := GoType(x.Element)
+		if elem == nil {
+			return nil
+		}
+		if x.Slices == 0 {
+			return types.NewArray(elem, int64(x.Len))
+		}
+		for i := 0; i < x.Slices; i++ {
+			elem = types.NewSlice(elem)
+		}
+		return elem
+	case *mapType:
+		key, val := GoType(x.key), GoType(x.val)
+		if key == nil || val == nil {
+			return nil
+		}
+		return types.NewMap(key, val)
+	case *chanType:
+		elem := GoType(x.elem)
+		if elem == nil {
+			return nil
+		}
+		dir := types.SendRecv
+		switch x.dir {
+		case ast.SEND:
+			dir = types.SendOnly
+		case ast.RECV:
+			dir = types.RecvOnly
+		}
+		return types.NewChan(dir, elem)
+	case *FuncType:
+		params, results := goTuple(x.Params), goTuple(x.Results)
+		if params == nil || results == nil {
+			return nil
+		}
+		return types.NewSignatureType(nil, nil, nil, params, results, x.Variadic)
+	}
+	return nil
+}
+
+// goTuple returns the tuple of the params or results, nil if some is not type checked
+func goTuple(fields []*Field) *types.Tuple {
+	vars := make([]*types.Var, len(fields))
+	for i, field := range fields {
+		ResolveUnknownField(field)
+		t := GoType(field.Type)
+		if t == nil {
+			return nil
+		}
+		vars[i] = types.NewParam(token.NoPos, nil, field.name, t)
+	}
+	return types.NewTuple(vars...)
+}
+
+// goTypes returns the go/types types of both, ok is false if either is not type checked,
+// or neither refers to a type checked named type, so nothing changes without the PackagesLoader
+func goTypes(t Type, tt Type) (x types.Type, y types.Type, ok bool) {
+	if !hasNamed(t) && !hasNamed(tt) {
+		return nil, nil, false
+	}
+	x, y = GoType(t), GoType(tt)
+	if x == nil || y == nil {
+		return nil, nil, false
+	}
+	return x, y, true
+}
+
+// hasNamed reports whether t refers to a type checked named type
+func hasNamed(t Type) bool {
+	switch x := t.(type) {
+	case *namedType:
+		return x.obj != nil
+	case *aliasType:
+		return x.alias != nil && hasNamed(x.alias)
+	case *filedType:
+		return hasNamed(x.Type)
+	case *exprType:
+		return hasNamed(x.Type)
+	case *Field:
+		return hasNamed(x.Type)
+	case *PointerType:
+		return hasNamed(x.Base)
+	case *ArrayType:
+		return hasNamed(x.Element)
+	case *mapType:
+		return hasNamed(x.key) || hasNamed(x.val)
+	case *chanType:
+		return hasNamed(x.elem)
+	case *FuncType:
+		for _, field := range append(append([]*Field{}, x.Params...), x.Results...) {
+			if hasNamed(field.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
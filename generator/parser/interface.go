@@ -2,9 +2,8 @@ package parser
 
 import (
 	"go/ast"
-	"log"
 
-	"github.com/lawrsp/pigo/generator/printutil"
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 type OldFuncType struct {
@@ -20,7 +19,7 @@ func NewOldFuncType(name string) *OldFuncType {
 		return &OldFuncType{Expr: expr}
 	}
 
-	log.Fatalf("func type name error:%s", name)
+	errutil.Throw(errutil.New("func type name error").WithExpr(name))
 	return nil
 
 }
@@ -28,7 +27,7 @@ func NewOldFuncType(name string) *OldFuncType {
 func (ft *OldFuncType) Fill(node *DeclNode) {
 	decl, ok := node.Node.(*ast.FuncDecl)
 	if !ok {
-		printutil.FatalNodef(node, "cannot fill OldFuncType:")
+		errutil.Throw(node.File.errorf(node.Node, "cannot fill OldFuncType with %T", node.Node))
 	}
 
 	ft.Name = decl.Name
@@ -138,7 +137,7 @@ func (i *OldInterface) parseFieldList(pkg *Package, list *ast.FieldList) []*OldF
 func (i *OldInterface) Fill(node *DeclNode) {
 	itf, ok := node.Node.(*ast.InterfaceType)
 	if !ok {
-		printutil.FatalNodef(node.Node, "type Error:")
+		errutil.Throw(node.File.errorf(node.Node, "cannot fill OldInterface with %T", node.Node))
 	}
 
 	i.OldFuncTypes = i.parseFieldList(node.File.BelongTo, itf.Methods)
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/lawrsp/pigo/generator/errutil"
+)
+
+// Loader finds the packages for the Parser, see BuildLoader and PackagesLoader
+type Loader interface {
+	// LoadDir loads the package in the directory
+	LoadDir(p *Parser, dir string) (*LoadedPackage, error)
+	// Import loads the package imported by path in srcDir
+	Import(p *Parser, path string, srcDir string) (*LoadedPackage, error)
+}
+
+// LoadedPackage is a package found by a Loader
+type LoadedPackage struct {
+	// Path is the canonical import path, empty if unknown
+	Path string
+	Dir  string
+	// GoFiles are parsed by the Parser if Syntax is nil
+	GoFiles []string
+	// Syntax are the files parsed in the FileSet of the Parser
+	Syntax []*ast.File
+
+	// Types and TypesInfo are nil if the package is not type checked
+	Types     *types.Package
+	TypesInfo *types.Info
+}
+
+// BuildLoader finds the packages with go/build, the files are parsed without type checking
+type BuildLoader struct{}
+
+func (BuildLoader) LoadDir(p *Parser, dir string) (*LoadedPackage, error) {
+	buildPkg, err := p.ImportContext.ImportDir(dir, 0)
+	if err != nil {
+		return nil, errutil.New("cannot process directory %s: %v", dir, err)
+	}
+	// TODO: Need to think about constants in test files. Maybe write type_string_test.go
+	// in a separate pass? For later.
+	// names = append(names, pkg.TestGoFiles...) // These are also in the "foo" package.
+	//	names = append(names, pkg.SFiles...)
+	return &LoadedPackage{
+		Dir:     dir,
+		GoFiles: p.overlaid(dir, prefixDirectory(dir, buildPkg.GoFiles)),
+	}, nil
+}
+
+// Import finds the package in the DiskCache first, if it is set
+func (BuildLoader) Import(p *Parser, path string, srcDir string) (*LoadedPackage, error) {
+	key := ""
+	if p.DiskCache != nil {
+		key = importKey(&p.ImportContext, path, srcDir)
+	}
+	if key != "" {
+		if entry, ok := p.DiskCache.getImport(key); ok {
+			return &LoadedPackage{
+				Path:    entry.Path,
+				Dir:     entry.Dir,
+				GoFiles: p.overlaid(entry.Dir, prefixDirectory(entry.Dir, entry.GoFiles)),
+			}, nil
+		}
+	}
+
+	buildPkg, err := p.ImportContext.Import(path, srcDir, 0)
+	if err != nil {
+		return nil, errutil.New("cannot import package %s in %s: %v", path, srcDir, err)
+	}
+
+	importPath := buildPkg.ImportPath
+	if buildPkg.ImportComment != "" && buildPkg.ImportComment != buildPkg.ImportPath {
+		importPath = buildPkg.ImportComment
+	}
+	if key != "" {
+		p.DiskCache.putImport(key, &importEntry{Path: importPath, Dir: buildPkg.Dir, GoFiles: buildPkg.GoFiles})
+	}
+
+	return &LoadedPackage{
+		Path:    importPath,
+		Dir:     buildPkg.Dir,
+		GoFiles: p.overlaid(buildPkg.Dir, prefixDirectory(buildPkg.Dir, buildPkg.GoFiles)),
+	}, nil
+}
+
+func (p *Parser) loader() Loader {
+	if p.Loader == nil {
+		return BuildLoader{}
+	}
+	return p.Loader
+}
+
+// files returns the files of the loaded package, parses the GoFiles if needed
+func (p *Parser) files(loaded *LoadedPackage, imported bool) ([]*File, error) {
+	if loaded.Syntax == nil {
+		files := []*File{}
+		for _, name := range loaded.GoFiles {
+			parsedFile, err := p.parseFile(name, imported)
+			if err != nil {
+				return nil, errutil.New("parsing package: %v", err)
+			}
+			files = append(files, &File{
+				parser: p,
+				Name:   name,
+				File:   parsedFile,
+			})
+		}
+		return files, nil
+	}
+
+	files := []*File{}
+	for _, syntax := range loaded.Syntax {
+		files = append(files, &File{
+			parser: p,
+			Name:   p.FileSet.Position(syntax.Package).Filename,
+			File:   syntax,
+		})
+	}
+	return files, nil
+}
+
+// newLoadedPackage makes the Package of the loaded one, imported is false for the package to generate in
+func (p *Parser) newLoadedPackage(loaded *LoadedPackage, path string, imported bool) (*Package, error) {
+	files, err := p.files(loaded, imported)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errutil.New("%s: no buildable Go files", loaded.Dir)
+	}
+
+	name := files[0].File.Name.Name
+	pkg, err := NewPackage(p, name, loaded.Dir, path, files)
+	if err != nil {
+		return nil, err
+	}
+	pkg.Types = loaded.Types
+	pkg.TypesInfo = loaded.TypesInfo
+	return pkg, nil
+}
@@ -3,8 +3,11 @@ package parser
 import (
 	"fmt"
 	"go/ast"
-	"log"
+	"go/types"
+	"path/filepath"
 	"strings"
+
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 type Package struct {
@@ -16,13 +19,17 @@ type Package struct {
 	PackageName   string
 	Files         []*File
 	parser        *Parser
+
+	// Types and TypesInfo are set if the package is type checked by the Loader
+	Types     *types.Package
+	TypesInfo *types.Info
 }
 
 func (pkg *Package) EqualTo(np *Package) bool {
 	return pkg.Scope == np.Scope
 }
 
-func NewPackage(p *Parser, name string, dir string, path string, files []*File) *Package {
+func NewPackage(p *Parser, name string, dir string, path string, files []*File) (*Package, error) {
 	pkg := &Package{}
 	astFiles := map[string]*ast.File{}
 	pkgFiles := []*File{}
@@ -34,17 +41,12 @@ func NewPackage(p *Parser, name string, dir string, path string, files []*File)
 		astFiles[f.Name] = cf.File
 	}
 
-	canonicalPath, scope := p.ImportScope(path, dir)
-	if scope == nil {
-		scope = NewScope()
-		p.AddScope(canonicalPath, scope)
-	}
-
-	if scope == nil {
-		log.Fatalf("cannot create package %s:%s", name, path)
+	canonicalPath, _, err := p.ImportScope(path, dir)
+	if err != nil {
+		return nil, err
 	}
 
-	pkg.Scope = scope
+	pkg.Scope = p.scope(canonicalPath)
 	pkg.Name = name
 	pkg.Dir = dir
 	pkg.Path = path
@@ -57,7 +59,7 @@ func NewPackage(p *Parser, name string, dir string, path string, files []*File)
 		pkg.PackageName = name
 	}
 
-	return pkg
+	return pkg, nil
 }
 
 func (pkg *Package) InsertFile(file *File, index int) {
@@ -90,7 +92,7 @@ func (this *Package) mergePackage(pkg *Package) {
 	}
 
 	if pkg.Name != this.Name {
-		log.Fatalf("different package name")
+		errutil.Throwf("cannot merge package %s into %s: different package name", pkg.Name, this.Name)
 	}
 
 	for _, f := range pkg.Files {
@@ -112,10 +114,16 @@ func (pkg *Package) mergeFile(f *File) {
 	pkg.Files = append(pkg.Files, f)
 }
 
+// GetFile finds the file by its path or its base name
 func (pkg *Package) GetFile(name string) *File {
+	name = filepath.Clean(name)
+	abs, _ := filepath.Abs(name)
 	for _, f := range pkg.Files {
-		fnames := strings.Split(f.Name, "/")
-		if name == fnames[len(fnames)-1] {
+		if name == filepath.Clean(f.Name) || name == filepath.Base(f.Name) {
+			return f
+		}
+		// the files of the PackagesLoader have absolute names
+		if fabs, err := filepath.Abs(f.Name); err == nil && fabs == abs {
 			return f
 		}
 	}
@@ -126,13 +134,13 @@ func (pkg *Package) GetFile(name string) *File {
 func (pkg *Package) FindDecl(name string, finder DeclFinder) *DeclNode {
 	idents := strings.Split(name, ".")
 	if len(idents) > 2 {
-		log.Fatalf("not support name: %s", name)
+		errutil.Throw(errutil.New("not supported name").WithExpr(name))
 	}
 
 	//find in file
 	expr, err := ParseExpr(name)
 	if err != nil {
-		log.Fatalf("parse expr(%s) failed: %v", name, err)
+		errutil.Throw(errutil.New("parse expr failed: %v", err).WithExpr(name))
 	}
 
 	for _, f := range pkg.Files {
@@ -157,7 +165,7 @@ func (pkg *Package) FindFuncDecl(name string) *DeclNode {
 			return nil
 		}
 
-		typ := ParseType(node.Node)
+		typ := parseType(node.Node)
 		receiverName := typ.Name()
 		funName := idents[2]
 		// log.Printf("find receiver: %s, funName: %s", receiverName, funName)
@@ -176,7 +184,7 @@ func (pkg *Package) FindFuncDecl(name string) *DeclNode {
 		}
 	}
 
-	log.Fatalf("not supported function name: %s", name)
+	errutil.Throw(errutil.New("not supported function name").WithExpr(name))
 	return nil
 }
 
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"go/ast"
+	"go/build"
+	"go/types"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/lawrsp/pigo/generator/errutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// the packages are type checked by the PackagesLoader, not go/packages,
+// it asserts the sizes of the go command to *types.StdSizes, which are not since go1.21
+const packagesMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedSyntax
+
+// PackagesLoader loads the packages with golang.org/x/tools/go/packages,
+// so the packages are found as the go command does, with modules, replace directives,
+// vendoring, workspaces, build tags and cgo, and they are type checked
+type PackagesLoader struct {
+	// BuildFlags and Env are passed to the go command
+	BuildFlags []string
+	Env        []string
+
+	// the packages of the last LoadDir, and their dependencies
+	byDir  map[string]*packages.Package
+	byPath map[string]*packages.Package
+	// mu serializes the loads, the packages are type checked in place
+	mu sync.Mutex
+}
+
+func NewPackagesLoader() *PackagesLoader {
+	return &PackagesLoader{
+		byDir:  map[string]*packages.Package{},
+		byPath: map[string]*packages.Package{},
+	}
+}
+
+// LoadDir always loads the package again, the files may be changed by the generators
+func (l *PackagesLoader) LoadDir(p *Parser, dir string) (*LoadedPackage, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errutil.New("cannot process directory %s: %v", dir, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pkg, err := l.load(p, abs, ".")
+	if err != nil {
+		return nil, errutil.New("cannot process directory %s: %v", dir, err)
+	}
+	return l.loaded(pkg, dir)
+}
+
+// Import finds the package in the imports of the package in srcDir first
+func (l *PackagesLoader) Import(p *Parser, path string, srcDir string) (*LoadedPackage, error) {
+	abs, err := filepath.Abs(srcDir)
+	if err != nil {
+		return nil, errutil.New("cannot import package %s in %s: %v", path, srcDir, err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if importer := l.byDir[abs]; importer != nil {
+		if importer.PkgPath == path {
+			return l.loaded(importer, abs)
+		}
+		if pkg := importer.Imports[path]; pkg != nil {
+			return l.loaded(pkg, packageDir(pkg))
+		}
+	}
+	if pkg := l.byPath[path]; pkg != nil {
+		return l.loaded(pkg, packageDir(pkg))
+	}
+
+	pkg, err := l.load(p, abs, path)
+	if err != nil {
+		return nil, errutil.New("cannot import package %s in %s: %v", path, srcDir, err)
+	}
+	return l.loaded(pkg, packageDir(pkg))
+}
+
+func (l *PackagesLoader) load(p *Parser, dir string, pattern string) (*packages.Package, error) {
+	config := &packages.Config{
+		Mode:       packagesMode,
+		Dir:        dir,
+		Fset:       p.FileSet,
+		BuildFlags: l.BuildFlags,
+		Env:        l.Env,
+		Overlay:    p.CopyOverlay(),
+	}
+	pkgs, err := packages.Load(config, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, errutil.New("%d packages matched", len(pkgs))
+	}
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		l.byPath[pkg.PkgPath] = pkg
+		if dir := packageDir(pkg); dir != "" {
+			l.byDir[dir] = pkg
+		}
+	})
+	l.check(p, pkgs[0], l.sizes())
+	return pkgs[0], nil
+}
+
+// check type checks the package after its imports, the type errors are ignored
+func (l *PackagesLoader) check(p *Parser, pkg *packages.Package, sizes types.Sizes) *types.Package {
+	if pkg.Types != nil {
+		return pkg.Types
+	}
+	if pkg.PkgPath == "unsafe" {
+		pkg.Types = types.Unsafe
+		return pkg.Types
+	}
+
+	config := &types.Config{
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			imported := pkg.Imports[path]
+			if imported == nil {
+				return nil, errutil.New("missing package: %q", path)
+			}
+			return l.check(p, imported, sizes), nil
+		}),
+		Sizes: sizes,
+		Error: func(error) {},
+	}
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Instances:  map[*ast.Ident]types.Instance{},
+		Scopes:     map[ast.Node]*types.Scope{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+	}
+	pkg.Types, _ = config.Check(pkg.PkgPath, p.FileSet, pkg.Syntax, info)
+	pkg.TypesInfo = info
+	pkg.TypesSizes = sizes
+	return pkg.Types
+}
+
+// sizes are of the GOARCH in Env, or of the default one
+func (l *PackagesLoader) sizes() types.Sizes {
+	goarch := build.Default.GOARCH
+	for _, env := range l.Env {
+		if strings.HasPrefix(env, "GOARCH=") {
+			goarch = strings.TrimPrefix(env, "GOARCH=")
+		}
+	}
+	if sizes := types.SizesFor("gc", goarch); sizes != nil {
+		return sizes
+	}
+	return types.SizesFor("gc", "amd64")
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// loaded returns the loaded package, the type errors are ignored,
+// the types of the generated code are not known before it is written
+func (l *PackagesLoader) loaded(pkg *packages.Package, dir string) (*LoadedPackage, error) {
+	if len(pkg.Syntax) == 0 {
+		if len(pkg.Errors) > 0 {
+			return nil, pkg.Errors[0]
+		}
+		return nil, errutil.New("%s: no buildable Go files", dir)
+	}
+
+	return &LoadedPackage{
+		Path:      pkg.PkgPath,
+		Dir:       dir,
+		GoFiles:   pkg.CompiledGoFiles,
+		Syntax:    pkg.Syntax,
+		Types:     pkg.Types,
+		TypesInfo: pkg.TypesInfo,
+	}, nil
+}
+
+func packageDir(pkg *packages.Package) string {
+	if len(pkg.GoFiles) > 0 {
+		return filepath.Dir(pkg.GoFiles[0])
+	}
+	if len(pkg.CompiledGoFiles) > 0 {
+		return filepath.Dir(pkg.CompiledGoFiles[0])
+	}
+	return ""
+}
@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPackagesLoader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// b is only found by the replace directive
+	writeFiles(t, dir, map[string]string{
+		"go.mod":     "module example.com/a\n\ngo 1.18\n\nrequire example.com/b v0.0.0\n\nreplace example.com/b => ./b\n",
+		"a.go":       "package a\n\nimport \"example.com/b\"\n\ntype A struct {\n\tX b.T\n\tY b.U\n\tZ int\n}\n\ntype I int\n\nconst N I = 1\n",
+		"b/go.mod":   "module example.com/b\n\ngo 1.18\n",
+		"b/b.go":     "package b\n\ntype T int\n\ntype U = T\n",
+		"b/other.go": "package b\n\ntype V int\n",
+	})
+
+	p := NewParser()
+	p.Loader = NewPackagesLoader()
+	pkg, err := p.ParsePackageDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, pkg.TypesInfo != nil, "package should be type checked")
+	expect(t.Errorf, pkg.CanonicalPath, "example.com/a")
+
+	// the outputs are given relative to the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, filepath.Join(dir, "a.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, pkg.GetFile(rel) != nil, "%s should be found", rel)
+
+	file := pkg.Files[0]
+	reduce := func(src string) Type {
+		expr, err := ParseExpr(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return file.ReduceType(expr)
+	}
+
+	a := reduce("A")
+	st := a.Underlying().(*StructType)
+	x, y, z := st.Fields[0], st.Fields[1], st.Fields[2]
+	ResolveUnknownField(x)
+	ResolveUnknownField(y)
+	ResolveUnknownField(z)
+
+	expect(t.Errorf, GoType(x.Type).String(), "example.com/b.T")
+	assert(t, x.Type.EqualTo(y.Type), "alias b.U should be identical to b.T")
+	assert(t, !x.Type.EqualTo(reduce("b.V")), "b.V should not be b.T")
+	assert(t, !x.Type.EqualTo(z.Type), "b.T should not be int")
+	assert(t, !reduce("I").AssignableTo(z.Type), "I should not be assignable to int")
+	assert(t, TypeAssignable(reduce("*A"), reduce("*A")), "*A should be assignable to *A")
+
+	// without the loader, the same package has no types
+	p = NewParser()
+	pkg, err = p.ParsePackageDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert(t, pkg.TypesInfo == nil, "package should not be type checked")
+}
@@ -5,16 +5,38 @@ import (
 	"go/build"
 	"go/parser"
 	"go/token"
-	"log"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
+// Parser parses the packages and keeps them by import path, it can be used by the goroutines at once,
+// but a Package returned is not shared, the generators change its files
 type Parser struct {
 	FileSet       *token.FileSet
 	Scope         *ast.Scope
 	ImportContext build.Context
-	Scopes        map[string]*Scope
+	// Scopes are the packages parsed by canonical import path, so the types of a package are identical,
+	// it should not be changed while the parser is used, see AddScope
+	Scopes map[string]*Scope
+	// Loader finds the packages, BuildLoader if nil
+	Loader Loader
+	// Overlay replaces the contents of the files by absolute path,
+	// the files not on disk are added to the packages of their directories, see AddOverlay
+	Overlay map[string][]byte
+	// Cache keeps the parsed files until they are forgotten, see Forget,
+	// the files are not parsed again when the packages are, except by the PackagesLoader
+	Cache bool
+	// DiskCache keeps the imported packages on disk for the next runs, nil to parse them every time,
+	// it is used by the BuildLoader only, the PackagesLoader type checks the whole files
+	DiskCache *DiskCache
+
+	cached map[cachedFile]*ast.File
+	// mu guards Scopes, Overlay and cached
+	mu sync.Mutex
 }
 
 func NewParser() *Parser {
@@ -26,36 +48,187 @@ func NewParser() *Parser {
 	}
 }
 
-func (p *Parser) ImportScope(path string, dir string) (canonicalPath string, scope *Scope) {
+func (p *Parser) ImportScope(path string, dir string) (canonicalPath string, scope *Scope, err error) {
 	if path == "" {
-		return "", nil
+		return "", nil, nil
 	}
 
-	buildPkg, err := p.ImportContext.Import(path, dir, 0)
+	loaded, err := p.loader().Import(p, path, dir)
 	if err != nil {
-		log.Fatalf("cannot import package: %s, %s:%v", path, dir, err)
+		return "", nil, err
 	}
 
-	importPath := buildPkg.ImportPath //path
+	importPath := loaded.Path
 
 	//history equal
 	if importPath == "golang.org/x/net/context" {
 		importPath = "context"
 	}
 
-	if buildPkg.ImportComment != "" && buildPkg.ImportComment != buildPkg.ImportPath {
-		importPath = buildPkg.ImportComment
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if alt := p.Scopes[importPath]; alt != nil {
+		return importPath, alt, nil
 	}
 
-	if alt := p.Scopes[importPath]; alt != nil {
-		return importPath, alt
+	return importPath, nil, nil
+}
+
+// scope returns the scope of the package, a new one if it is not parsed yet,
+// the goroutines parsing a package at once get the same scope
+func (p *Parser) scope(canonicalPath string) *Scope {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if scope := p.Scopes[canonicalPath]; scope != nil {
+		return scope
 	}
+	scope := NewScope()
+	p.Scopes[canonicalPath] = scope
+	return scope
+}
 
-	return importPath, nil
+// AddOverlay replaces the contents of the file when it is parsed again
+func (p *Parser) AddOverlay(name string, content []byte) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return errutil.New("overlay %s: %v", name, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Overlay == nil {
+		p.Overlay = map[string][]byte{}
+	}
+	p.Overlay[abs] = content
+	return nil
+}
+
+// source returns the overlaid contents of the file, nil to read the file
+func (p *Parser) source(name string) interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.Overlay) == 0 {
+		return nil
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil
+	}
+	if content, ok := p.Overlay[abs]; ok {
+		return content
+	}
+	return nil
+}
+
+// overlaid adds the overlay files in dir, which are not in names
+func (p *Parser) overlaid(dir string, names []string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.Overlay) == 0 {
+		return names
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return names
+	}
+	exists := map[string]bool{}
+	for _, name := range names {
+		if abs, err := filepath.Abs(name); err == nil {
+			exists[abs] = true
+		}
+	}
+	added := []string{}
+	for abs := range p.Overlay {
+		if filepath.Dir(abs) == absDir && strings.HasSuffix(abs, ".go") && !exists[abs] {
+			added = append(added, filepath.Join(dir, filepath.Base(abs)))
+		}
+	}
+	sort.Strings(added)
+	return append(names, added...)
+}
+
+// CopyOverlay returns a copy of the Overlay, nil if empty,
+// it is safe while the generators running at once add their outputs, see AddOverlay
+func (p *Parser) CopyOverlay() map[string][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.Overlay) == 0 {
+		return nil
+	}
+	overlay := make(map[string][]byte, len(p.Overlay))
+	for name, content := range p.Overlay {
+		overlay[name] = content
+	}
+	return overlay
+}
+
+// cachedFile is the key of a parsed file, decls is true if only its declarations are parsed, see DiskCache
+type cachedFile struct {
+	name  string
+	decls bool
+}
+
+// parseFile parses the file, or returns the cached one, the overlaid files are not cached,
+// only the declarations of the imported files are parsed if the DiskCache is set
+func (p *Parser) parseFile(name string, imported bool) (*ast.File, error) {
+	src := p.source(name)
+	decls := imported && src == nil && p.DiskCache != nil
+	parse := func() (*ast.File, error) {
+		if decls {
+			return p.DiskCache.parseDecls(p.FileSet, name)
+		}
+		return parser.ParseFile(p.FileSet, name, src, parser.ParseComments)
+	}
+	if !p.Cache || src != nil {
+		return parse()
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	key := cachedFile{name: abs, decls: decls}
+	p.mu.Lock()
+	file, ok := p.cached[key]
+	p.mu.Unlock()
+	if ok {
+		return file, nil
+	}
+	file, err = parse()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// parsed by another goroutine meanwhile
+	if cached, ok := p.cached[key]; ok {
+		return cached, nil
+	}
+	if p.cached == nil {
+		p.cached = map[cachedFile]*ast.File{}
+	}
+	p.cached[key] = file
+	return file, nil
+}
+
+// Forget drops the files from the cache, they are parsed again when used,
+// the resolved types of all packages are dropped too, they may refer to the types in the files
+func (p *Parser) Forget(names ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, name := range names {
+		if abs, err := filepath.Abs(name); err == nil {
+			delete(p.cached, cachedFile{name: abs})
+			delete(p.cached, cachedFile{name: abs, decls: true})
+		}
+	}
+	p.Scopes = map[string]*Scope{}
 }
 
 func (p *Parser) AddScope(canonicalPath string, scope *Scope) {
 	// log.Printf("!=====add %s: %p", canonicalPath, scope)
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.Scopes[canonicalPath] = scope
 }
 
@@ -63,36 +236,37 @@ func (p *Parser) PrintNode(node ast.Node) {
 	ast.Print(p.FileSet, node)
 }
 
-func (p *Parser) ParsePackageDir(directory string) *Package {
-	pkg, err := build.Default.ImportDir(directory, 0)
+// Position returns the position of pos in the parsed files
+func (p *Parser) Position(pos token.Pos) token.Position {
+	if p == nil || !pos.IsValid() {
+		return token.Position{}
+	}
+	return p.FileSet.Position(pos)
+}
+
+func (p *Parser) ParsePackageDir(directory string) (*Package, error) {
+	loaded, err := p.loader().LoadDir(p, directory)
 	if err != nil {
-		log.Fatalf("cannot process directory %s: %s", directory, err)
+		return nil, err
 	}
-	var names []string
-	names = append(names, pkg.GoFiles...)
-	// TODO: Need to think about constants in test files. Maybe write type_string_test.go
-	// in a separate pass? For later.
-	// names = append(names, pkg.TestGoFiles...) // These are also in the "foo" package.
-	//	names = append(names, pkg.SFiles...)
-	names = prefixDirectory(directory, names)
-	return p.ParsePackage("", directory, names)
+	return p.newLoadedPackage(loaded, loaded.Path, false)
 }
 
 // parsePackageFiles parses the package occupying the named files.
-func (p *Parser) ParsePackageFiles(names []string) *Package {
+func (p *Parser) ParsePackageFiles(names []string) (*Package, error) {
 	return p.ParsePackage("", ".", names)
 }
 
-func (p *Parser) ParseFileContent(name string, content interface{}) *File {
+func (p *Parser) ParseFileContent(name string, content interface{}) (*File, error) {
 	parsedFile, err := parser.ParseFile(p.FileSet, name, content, parser.ParseComments)
 	if err != nil {
-		log.Fatalf("parsing package: %s: %s", name, err)
+		return nil, errutil.New("parsing file %s: %v", name, err)
 	}
 
 	return &File{
 		Name: name,
 		File: parsedFile,
-	}
+	}, nil
 }
 
 func (p *Parser) InsertFileToPackage(pkg *Package, file *File, index int) {
@@ -118,17 +292,16 @@ func prefixDirectory(directory string, names []string) []string {
 }
 
 // parsePackage analyzes the single package constructed from the named files.
-func (p *Parser) ParsePackage(path string, directory string, names []string) *Package {
+func (p *Parser) ParsePackage(path string, directory string, names []string) (*Package, error) {
 
-	mod := parser.ParseComments
 	var files = []*File{}
 	for _, name := range names {
 		if !strings.HasSuffix(name, ".go") {
 			continue
 		}
-		parsedFile, err := parser.ParseFile(p.FileSet, name, nil, mod)
+		parsedFile, err := p.parseFile(name, false)
 		if err != nil {
-			log.Fatalf("parsing package: %s: %s", name, err)
+			return nil, errutil.New("parsing package: %v", err)
 		}
 		// astFiles[name] = parsedFile
 		files = append(files, &File{
@@ -139,27 +312,25 @@ func (p *Parser) ParsePackage(path string, directory string, names []string) *Pa
 		})
 	}
 	if len(files) == 0 {
-		log.Fatalf("%s: no buildable Go files", directory)
+		return nil, errutil.New("%s: no buildable Go files", directory)
 	}
 
 	name := files[0].File.Name.Name
-	pkg := NewPackage(p, name, directory, path, files)
-
-	return pkg
+	return NewPackage(p, name, directory, path, files)
 }
 
-func (p *Parser) ImportPackage(name string, path string, srcDir string) *Package {
-	buildPkg, err := build.Default.Import(path, srcDir, 0)
+func (p *Parser) ImportPackage(name string, path string, srcDir string) (*Package, error) {
+	loaded, err := p.loader().Import(p, path, srcDir)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	dir := buildPkg.Dir
-	names := []string{}
-	names = append(names, buildPkg.GoFiles...)
 
-	pkg := p.ParsePackage(path, dir, prefixDirectory(dir, names))
+	pkg, err := p.newLoadedPackage(loaded, path, true)
+	if err != nil {
+		return nil, err
+	}
 	pkg.Name = name
-	return pkg
+	return pkg, nil
 }
 
 func ParseExpr(x string) (ast.Expr, error) {
@@ -3,7 +3,9 @@ package parser
 import (
 	"fmt"
 	"go/ast"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"testing"
@@ -56,7 +58,7 @@ func TestType(t *testing.T) {
 	expect(t, err, nil)
 	ast.Print(fs, expr)
 
-	typ := ParseType(expr)
+	typ, _ := ParseType(expr)
 
 	expect(t, typ.String(), src)
 }*/
@@ -160,7 +162,7 @@ type X interface {
 
 func TestParsePackage(t *testing.T) {
 	p := NewParser()
-	pkg := p.ParsePackageDir("./")
+	pkg, _ := p.ParsePackageDir("./")
 	code := `
 package parser
 
@@ -169,7 +171,7 @@ import "github.com/lawrsp/pigo/generatorbuilder"
 import "strings"
 import "go/ast"
 `
-	file := p.ParseFileContent("_test", code)
+	file, _ := p.ParseFileContent("_test", code)
 	p.InsertFileToPackage(pkg, file, 1)
 	// fmt.Println("packageName:", pkg.Name)
 	// scope := pkg.Package.Scope
@@ -206,7 +208,7 @@ import "go/ast"
 	fmt.Println(typ.String())
 	expect(t.Errorf, typ.File() != nil, true)
 	expect(t.Errorf, typ.File().Name != "", true)
-	expect(t.Errorf, typ.String(), "parser.ImportScope(*parser.Parser,string,string)(string,*parser.Scope)")
+	expect(t.Errorf, typ.String(), "parser.ImportScope(*parser.Parser,string,string)(string,*parser.Scope,error)")
 
 	src = "[]int"
 	expr, err = ParseExpr(src)
@@ -308,9 +310,45 @@ import "go/ast"
 
 }
 
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFiles(t, dir, map[string]string{
+		"a.go": "package a\n\ntype A int\n",
+		"b.go": "package a\n\ntype B int\n",
+	})
+
+	p := NewParser()
+	p.Cache = true
+	parse := func() *Package {
+		pkg, err := p.ParsePackageDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pkg
+	}
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	first := parse()
+
+	// the changed files are parsed again only when they are forgotten
+	writeFiles(t, dir, map[string]string{"a.go": "package a\n\ntype A string\n"})
+	second := parse()
+	assert(t, second.GetFile(a).File == first.GetFile(a).File, "a.go should be cached")
+
+	p.Forget(a)
+	third := parse()
+	assert(t, third.GetFile(a).File != first.GetFile(a).File, "a.go should be parsed again")
+	assert(t, third.GetFile(b).File == first.GetFile(b).File, "b.go should be cached")
+	expect(t.Errorf, third.GetFile(a).File.Decls[0].(*ast.GenDecl).Specs[0].(*ast.TypeSpec).Type.(*ast.Ident).Name, "string")
+}
+
 func TestTypeToString(t *testing.T) {
 	p := NewParser()
-	pkg := p.ParsePackageDir("./")
+	pkg, _ := p.ParsePackageDir("./")
 	code := `
 package parser
 
@@ -324,7 +362,7 @@ type CustomerInterface interface{}
 
 var myinterface CustomerInterface
 `
-	file := p.ParseFileContent("_test", code)
+	file, _ := p.ParseFileContent("_test", code)
 	p.InsertFileToPackage(pkg, file, 1)
 
 	src := "TypeToStringStruct"
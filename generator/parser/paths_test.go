@@ -7,7 +7,7 @@ import (
 
 func TestTypeToType(t *testing.T) {
 	p := NewParser()
-	pkg := p.ParsePackageDir("./")
+	pkg, _ := p.ParsePackageDir("./")
 	code := `
 package parser
 
@@ -58,7 +58,7 @@ func B2A(src *B) (*A, error) {
 	return A, nil
 }
 `
-	file := p.ParseFileContent("_test", code)
+	file, _ := p.ParseFileContent("_test", code)
 	p.InsertFileToPackage(pkg, file, 1)
 
 	knowns := []*TPath{}
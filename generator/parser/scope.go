@@ -92,4 +92,8 @@ func init() {
 	// The same applies to rune.
 	UniverseScope.Objects["rune"] = UniverseScope.Objects["uint32"]
 	Universe.Objects["rune"] = Universe.Objects["uint32"]
+
+	// any is an alias for interface{}
+	UniverseScope.Objects["any"] = UniverseScope.Objects["interface"]
+	Universe.Objects["any"] = Universe.Objects["interface"]
 }
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"go/ast"
 	"go/token"
-	"log"
+	"go/types"
 	"strings"
+
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 type Type interface {
@@ -24,7 +26,7 @@ type Type interface {
 
 func IsAtomicType(t Type) bool {
 	switch t.(type) {
-	case *BasicType, *mapType, *ArrayType:
+	case *BasicType, *mapType, *ArrayType, *chanType:
 		return true
 	default:
 		return false
@@ -81,7 +83,7 @@ func (t *BasicType) DefaultValue() ast.Expr {
 	case "error", "interface":
 		return &ast.Ident{Name: "nil"}
 	default:
-		log.Fatalf("donot support basic type: %s.DefaultValue()", t.name)
+		errutil.Throwf("donot support basic type: %s.DefaultValue()", t.name)
 		return nil
 	}
 }
@@ -128,9 +130,15 @@ func (t *PointerType) Name() string {
 	return ""
 }
 func (t *PointerType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
 	return t.Base.AssignableTo(tt)
 }
 func (t *PointerType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	if x, ok := tt.(*PointerType); ok {
 		return t.Stars == x.Stars && t.Base.EqualTo(x.Base)
 	}
@@ -182,6 +190,9 @@ func (t *ArrayType) String() string {
 	return fmt.Sprintf("[%d]%s", t.Len, t.Element.String())
 }
 func (t *ArrayType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
 	ttu := tt.Underlying()
 	if x, ok := ttu.(*ArrayType); ok {
 		return t.Slices == x.Slices && t.Element.EqualTo(x.Element)
@@ -190,6 +201,9 @@ func (t *ArrayType) AssignableTo(tt Type) bool {
 	return false
 }
 func (t *ArrayType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	if x, ok := tt.(*ArrayType); ok {
 		return t.Slices == x.Slices && t.Element.EqualTo(x.Element)
 	}
@@ -237,6 +251,9 @@ func (t *mapType) String() string {
 	return fmt.Sprintf("map[%s]%s", t.key.String(), t.val.String())
 }
 func (t *mapType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
 	if t.EqualTo(tt) {
 		return true
 	}
@@ -247,6 +264,9 @@ func (t *mapType) AssignableTo(tt Type) bool {
 	return false
 }
 func (t *mapType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	if x, ok := tt.(*mapType); ok {
 
 		return t.key.EqualTo(x.key) && t.val.EqualTo(x.val)
@@ -257,13 +277,16 @@ func (t *mapType) EqualTo(tt Type) bool {
 //Define:
 //FuncType
 type FuncType struct {
-	Receiver *Field
-	Params   []*Field
-	Results  []*Field
+	Receiver   *Field
+	TypeParams []*TypeParam
+	Params     []*Field
+	Results    []*Field
+	// Variadic is true if the last param is ...T, the type of which is []T
+	Variadic bool
 }
 
 func (t *FuncType) Copy() Type {
-	cp := &FuncType{}
+	cp := &FuncType{TypeParams: t.TypeParams, Variadic: t.Variadic}
 	if t.Receiver != nil {
 		cp.Receiver = t.Receiver.Copy().(*Field)
 	}
@@ -294,11 +317,37 @@ func (t *FuncType) File() *File {
 func (t *FuncType) Package() *Package {
 	return nil
 }
-func (t *FuncType) AssignableTo(Type) bool {
-	return false
+func (t *FuncType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
+	return t.EqualTo(tt) || t.EqualTo(tt.Underlying())
 }
-func (t *FuncType) EqualTo(Type) bool {
-	return false
+
+// EqualTo compares the signatures, the receivers and the names are ignored
+func (t *FuncType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
+	x, ok := tt.(*FuncType)
+	if !ok || t.Variadic != x.Variadic {
+		return false
+	}
+	return fieldsEqual(t.Params, x.Params) && fieldsEqual(t.Results, x.Results)
+}
+
+func fieldsEqual(a, b []*Field) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ResolveUnknownField(a[i])
+		ResolveUnknownField(b[i])
+		if !TypeEqual(a[i].Type, b[i].Type) {
+			return false
+		}
+	}
+	return true
 }
 func (t *FuncType) Underlying() Type {
 	return t
@@ -310,7 +359,11 @@ func (t *FuncType) String() string {
 		params = append(params, t.Receiver.Type.String())
 	}
 	if t.Params != nil {
-		for _, p := range t.Params {
+		for i, p := range t.Params {
+			if t.Variadic && i == len(t.Params)-1 {
+				params = append(params, "..."+TypeSkipBracket(p.Type, 1).String())
+				continue
+			}
 			params = append(params, p.Type.String())
 		}
 	}
@@ -340,6 +393,8 @@ type Field struct {
 	Type
 	Tag  string
 	name string
+	// pos is where the field is declared, invalid if it is not from a parsed file
+	pos token.Position
 }
 
 func (t *Field) Copy() Type {
@@ -347,6 +402,7 @@ func (t *Field) Copy() Type {
 		Type: t.Type.Copy(),
 		Tag:  t.Tag,
 		name: t.name,
+		pos:  t.pos,
 	}
 	if nf.Type != nil {
 		nf.SetType(nf.Type)
@@ -395,6 +451,11 @@ func (t *Field) Name() string {
 	}
 }
 
+// Position returns where the field is declared
+func (t *Field) Position() token.Position {
+	return t.pos
+}
+
 func (t *Field) FieldName() string {
 	return t.name
 }
@@ -590,11 +651,106 @@ func (t *InterfaceType) GetFuncByName(name string) Type {
 	return nil
 }
 
-//@TODO:
+//Define:
+//TypeParam: the type parameter of a generic type or function
+type TypeParam struct {
+	name string
+	// Constraint is the expression of the constraint, e.g. any, ~int | ~string
+	Constraint ast.Expr
+}
+
+func (t *TypeParam) Copy() Type {
+	return &TypeParam{name: t.name, Constraint: t.Constraint}
+}
+func (t *TypeParam) File() *File {
+	return nil
+}
+func (t *TypeParam) Package() *Package {
+	return nil
+}
+func (t *TypeParam) Name() string {
+	return t.name
+}
+func (t *TypeParam) AssignableTo(tt Type) bool {
+	return t.EqualTo(tt)
+}
+func (t *TypeParam) EqualTo(tt Type) bool {
+	if x, ok := tt.(*TypeParam); ok {
+		return t.name == x.name
+	}
+	return false
+}
+func (t *TypeParam) Underlying() Type {
+	return t
+}
+func (t *TypeParam) String() string {
+	return t.name
+}
+
+//Define:
+//chanType
 type chanType struct {
-	base     Type
-	sender   bool
-	Receiver bool
+	elem Type
+	// dir is ast.SEND, ast.RECV or both
+	dir ast.ChanDir
+}
+
+func ChanType(elem Type, dir ast.ChanDir) Type {
+	return &chanType{elem: elem, dir: dir}
+}
+func (t *chanType) Copy() Type {
+	return &chanType{elem: t.elem.Copy(), dir: t.dir}
+}
+func (t *chanType) File() *File {
+	return nil
+}
+func (t *chanType) Package() *Package {
+	return nil
+}
+func (t *chanType) Name() string {
+	return ""
+}
+func (t *chanType) Elem() Type {
+	return t.elem
+}
+func (t *chanType) Dir() ast.ChanDir {
+	return t.dir
+}
+
+// AssignableTo allows a bidirectional channel to be assigned to a directional one
+func (t *chanType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
+	x, ok := tt.Underlying().(*chanType)
+	if !ok || !t.elem.EqualTo(x.elem) {
+		return false
+	}
+	return t.dir == x.dir || t.dir == ast.SEND|ast.RECV
+}
+func (t *chanType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
+	if x, ok := tt.(*chanType); ok {
+		return t.dir == x.dir && t.elem.EqualTo(x.elem)
+	}
+	return false
+}
+func (t *chanType) Underlying() Type {
+	return t
+}
+func (t *chanType) String() string {
+	switch t.dir {
+	case ast.SEND:
+		return fmt.Sprintf("chan<- %s", t.elem)
+	case ast.RECV:
+		return fmt.Sprintf("<-chan %s", t.elem)
+	}
+	return fmt.Sprintf("chan %s", t.elem)
+}
+func (t *chanType) DefaultValue() ast.Expr {
+	return &ast.Ident{Name: "nil"}
 }
 
 //wrapper types:
@@ -635,7 +791,7 @@ func (unknown *UnknownType) ResetParent(kn Type) {
 	case *Field:
 		p.SetType(kn)
 	default:
-		log.Fatalf("unsupported parent %s", unknown.Parent)
+		errutil.Throw(unknown.file.errorf(unknown.expr, "unsupported parent %s", unknown.Parent))
 	}
 }
 func (t *UnknownType) Name() string {
@@ -668,10 +824,23 @@ func (t *UnknownType) DefaultValue() ast.Expr {
 type namedType struct {
 	Type
 	name string
+	// obj is the go/types object, if the package is type checked
+	obj *types.TypeName
+
+	// params of a generic type, and args if it is instantiated
+	params []*TypeParam
+	args   []Type
+	// spec is the declaration of a generic type, to instantiate it
+	spec *ast.TypeSpec
+	// pos is where the type is declared, invalid if it is not from a parsed file
+	pos token.Position
 }
 
 func (t *namedType) Copy() Type {
-	cp := &namedType{name: t.name}
+	cp := &namedType{name: t.name, obj: t.obj, params: t.params, spec: t.spec, pos: t.pos}
+	for _, arg := range t.args {
+		cp.args = append(cp.args, arg.Copy())
+	}
 	if t.Type != nil {
 		tcp := t.Type.Copy()
 		cp.SetUnderlying(tcp)
@@ -692,6 +861,9 @@ func (t *namedType) Name() string {
 	return t.name
 }
 func (t *namedType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
 	if t.EqualTo(tt) {
 		return true
 	}
@@ -702,15 +874,30 @@ func (t *namedType) AssignableTo(tt Type) bool {
 	return false
 }
 func (t *namedType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	var ot Type = tt
 	if ft, ok := tt.(*filedType); ok {
 		ot = ft.Type
 	}
 	if ttu, ok := ot.(*namedType); ok {
-		return t.name == ttu.name
+		return t.name == ttu.name && typesEqual(t.typeArgs(), ttu.typeArgs())
 	}
 	return false
 }
+
+// typeArgs returns the type arguments, or the type parameters if it is not instantiated
+func (t *namedType) typeArgs() []Type {
+	if t.args != nil {
+		return t.args
+	}
+	args := make([]Type, len(t.params))
+	for i, param := range t.params {
+		args[i] = param
+	}
+	return args
+}
 func (t *namedType) Underlying() Type {
 	if t.Type != nil {
 		return t.Type.Underlying()
@@ -732,6 +919,13 @@ func (t *namedType) String() string {
 	if ft, ok := t.Type.(*FuncType); ok {
 		return fmt.Sprintf("%s%s", name, ft)
 	}
+	if args := t.typeArgs(); len(args) > 0 {
+		strs := make([]string, len(args))
+		for i, arg := range args {
+			strs[i] = arg.String()
+		}
+		return fmt.Sprintf("%s[%s]", name, strings.Join(strs, ","))
+	}
 
 	// return fmt.Sprintf("%s %s", name, t.Type)
 
@@ -773,12 +967,18 @@ func (t *aliasType) Name() string {
 	return t.name
 }
 func (t *aliasType) AssignableTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.AssignableTo(x, y)
+	}
 	if utt, ok := tt.(*aliasType); ok {
 		return t.alias.EqualTo(utt.alias)
 	}
 	return t.alias.EqualTo(tt)
 }
 func (t *aliasType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	if ttu, ok := tt.(*aliasType); ok {
 		return t.alias.EqualTo(ttu.alias)
 	}
@@ -828,6 +1028,9 @@ func (t *filedType) Package() *Package {
 	return t.file.BelongTo
 }
 func (t *filedType) EqualTo(tt Type) bool {
+	if x, y, ok := goTypes(t, tt); ok {
+		return types.Identical(x, y)
+	}
 	if IsAtomicType(t.Type) && IsAtomicType(tt) {
 		return t.Type.EqualTo(tt)
 	}
@@ -871,7 +1074,7 @@ func (t *filedType) PackageNameInFile(file *File) string {
 			pkgName = file.FindImportNameByPath(tPkg.Path)
 		}
 		if pkgName == "" {
-			log.Fatalf("package %s(%s) not imported in file(%s)", tPkg.Path, tPkg.CanonicalPath, file.Name)
+			errutil.Throwf("package %s(%s) not imported in file(%s)", tPkg.Path, tPkg.CanonicalPath, file.Name)
 		}
 		return pkgName
 	}
@@ -889,6 +1092,18 @@ func TypeEqual(a, b Type) bool {
 	return a.EqualTo(b)
 }
 
+func typesEqual(a, b []Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].EqualTo(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func TypeAssignable(target, source Type) bool {
 	if field, ok := target.(*Field); ok {
 		return TypeAssignable(field.Type, source)
@@ -898,6 +1113,10 @@ func TypeAssignable(target, source Type) bool {
 		return TypeAssignable(target, field.Type)
 	}
 
+	if x, y, ok := goTypes(source, target); ok {
+		return types.AssignableTo(x, y)
+	}
+
 	if basic, ok := target.(*BasicType); ok && basic.name == "interface" {
 		return true
 	}
@@ -907,5 +1126,9 @@ func TypeAssignable(target, source Type) bool {
 
 	// TODO: InterfaceType
 
+	if ch, ok := source.(*chanType); ok {
+		return ch.AssignableTo(target)
+	}
+
 	return target.EqualTo(source)
 }
@@ -7,7 +7,7 @@ import (
 	"log"
 	"strings"
 
-	"github.com/lawrsp/pigo/generator/printutil"
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 func NewType(name string) Type {
@@ -268,6 +268,10 @@ func TypeWithFile(t Type, file *File) Type {
 		return t
 	case *UnknownType:
 		return t
+	case *TypeParam:
+		return t
+	case *chanType:
+		return t
 		// default:
 		//	if t.Underlying() == t {
 		//		return t
@@ -282,6 +286,76 @@ func TypeWithFile(t Type, file *File) Type {
 	return &filedType{t, file}
 }
 
+// TypeParams returns the type parameters of a generic type or function, nil if it is not generic
+func TypeParams(t Type) []*TypeParam {
+	switch x := t.(type) {
+	case *filedType:
+		return TypeParams(x.Type)
+	case *Field:
+		return TypeParams(x.Type)
+	case *namedType:
+		if ft, ok := x.Type.(*FuncType); ok {
+			return ft.TypeParams
+		}
+		return x.params
+	case *FuncType:
+		return x.TypeParams
+	}
+	return nil
+}
+
+// TypeArgs returns the type arguments of an instantiated generic type,
+// or the type parameters if it is not instantiated, nil if it is not generic
+func TypeArgs(t Type) []Type {
+	switch x := t.(type) {
+	case *filedType:
+		return TypeArgs(x.Type)
+	case *Field:
+		return TypeArgs(x.Type)
+	case *namedType:
+		if len(x.params) == 0 {
+			return nil
+		}
+		return x.typeArgs()
+	}
+	return nil
+}
+
+// isGeneric reports whether t is a generic type, which is not instantiated
+func isGeneric(t Type) bool {
+	if ft, ok := t.(*filedType); ok {
+		t = ft.Type
+	}
+	named, ok := t.(*namedType)
+	return ok && named.spec != nil && named.args == nil
+}
+
+func typeParamList(list *ast.FieldList) []*TypeParam {
+	params := []*TypeParam{}
+	for _, field := range list.List {
+		for _, name := range field.Names {
+			params = append(params, &TypeParam{name: name.Name, Constraint: field.Type})
+		}
+	}
+	return params
+}
+
+func typeParamNames(params []*TypeParam) []string {
+	names := make([]string, len(params))
+	for i, param := range params {
+		names[i] = param.name
+	}
+	return names
+}
+
+func typeParamsAsArgs(params []*TypeParam) []Type {
+	args := make([]Type, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
+	return args
+}
+
 func GetTypeStars(t Type) int {
 	if x, ok := t.Underlying().(*PointerType); ok {
 		return x.Stars
@@ -294,12 +368,12 @@ func TypeExprInFile(t Type, file *File) ast.Expr {
 		return nil
 	}
 	if _, ok := t.(*UnknownType); ok {
-		log.Fatalf("field is unknown, there is a bug in ReduceType")
+		errutil.Throw(errutil.New("field is unknown, there is a bug in ReduceType").WithExpr(ExprToString(t.(*UnknownType).expr)))
 		return nil
 	}
 
 	if _, ok := t.(*Field); ok {
-		log.Fatalf("field Type %s cannot change to Expr", t)
+		errutil.Throwf("field Type %s cannot change to Expr", t)
 		return nil
 	}
 	if et, ok := t.(*exprType); ok {
@@ -356,6 +430,11 @@ func TypeExprInFile(t Type, file *File) ast.Expr {
 			Key:   TypeExprInFile(tm.key, file),
 			Value: TypeExprInFile(tm.val, file),
 		}
+	case *chanType:
+		return &ast.ChanType{
+			Dir:   tm.dir,
+			Value: TypeExprInFile(tm.elem, file),
+		}
 	case *FuncType:
 		fnt := &ast.FuncType{}
 		if tm.Params != nil && len(tm.Params) > 0 {
@@ -367,7 +446,11 @@ func TypeExprInFile(t Type, file *File) ast.Expr {
 				if field.name != "" {
 					astField.Names = []*ast.Ident{ast.NewIdent(field.name)}
 				}
-				astField.Type = TypeExprInFile(field.Type, file)
+				if tm.Variadic && i == len(tm.Params)-1 {
+					astField.Type = &ast.Ellipsis{Elt: TypeExprInFile(TypeSkipBracket(field.Type, 1), file)}
+				} else {
+					astField.Type = TypeExprInFile(field.Type, file)
+				}
 				params.List[i] = astField
 			}
 			fnt.Params = params
@@ -414,13 +497,27 @@ func TypeExprInFile(t Type, file *File) ast.Expr {
 		}
 		return expr
 	case *namedType:
-		if pkgName == "" {
-			return ast.NewIdent(tm.name)
+		var expr ast.Expr = ast.NewIdent(tm.name)
+		if pkgName != "" {
+			expr = &ast.SelectorExpr{
+				X:   ast.NewIdent(pkgName),
+				Sel: expr.(*ast.Ident),
+			}
 		}
-		return &ast.SelectorExpr{
-			X:   ast.NewIdent(pkgName),
-			Sel: ast.NewIdent(tm.name),
+		if len(tm.params) == 0 {
+			return expr
+		}
+		//Generic[T] as a receiver, or instantiated Generic[Arg]
+		indices := []ast.Expr{}
+		for _, arg := range tm.typeArgs() {
+			indices = append(indices, TypeExprInFile(arg, file))
 		}
+		if len(indices) == 1 {
+			return &ast.IndexExpr{X: expr, Index: indices[0]}
+		}
+		return &ast.IndexListExpr{X: expr, Indices: indices}
+	case *TypeParam:
+		return ast.NewIdent(tm.name)
 
 	case *aliasType:
 		if pkgName == "" {
@@ -432,7 +529,7 @@ func TypeExprInFile(t Type, file *File) ast.Expr {
 		}
 	}
 
-	log.Fatalf("donot support expr for type: %s", t)
+	errutil.Throwf("donot support expr for type: %s", t)
 	return nil
 }
 
@@ -441,12 +538,12 @@ func TypeZeroValue(t Type, file *File) ast.Expr {
 		return nil
 	}
 	if _, ok := t.(*UnknownType); ok {
-		log.Fatalf("field is unknown, there is a bug in ReduceType")
+		errutil.Throw(errutil.New("field is unknown, there is a bug in ReduceType").WithExpr(ExprToString(t.(*UnknownType).expr)))
 		return nil
 	}
 
 	if _, ok := t.(*Field); ok {
-		log.Fatalf("field Type %s cannot change to Expr", t)
+		errutil.Throwf("field Type %s cannot change to Expr", t)
 		return nil
 	}
 	if et, ok := t.(*exprType); ok {
@@ -463,8 +560,10 @@ func TypeZeroValue(t Type, file *File) ast.Expr {
 	switch tm := ot.(type) {
 	case *BasicType:
 		return tm.DefaultValue()
-	case *ArrayType, *mapType, *PointerType, *FuncType, *InterfaceType:
+	case *ArrayType, *mapType, *PointerType, *FuncType, *InterfaceType, *chanType:
 		return ast.NewIdent("nil")
+	case *TypeParam:
+		return typeParamZeroValue(tm)
 	case *namedType, *aliasType:
 		if basic, ok := tm.Underlying().(*BasicType); ok {
 			return &ast.CallExpr{
@@ -482,7 +581,7 @@ func TypeZeroValue(t Type, file *File) ast.Expr {
 		// 	log.Printf("tm is :%v(%v)", tm)
 	}
 
-	log.Fatalf("donot support zero value for type: %s", t)
+	errutil.Throwf("donot support zero value for type: %s", t)
 	return nil
 }
 
@@ -491,12 +590,12 @@ func TypeInitValue(t Type, file *File) ast.Expr {
 		return nil
 	}
 	if _, ok := t.(*UnknownType); ok {
-		log.Fatalf("field is unknown, there is a bug in ReduceType")
+		errutil.Throw(errutil.New("field is unknown, there is a bug in ReduceType").WithExpr(ExprToString(t.(*UnknownType).expr)))
 		return nil
 	}
 
 	if _, ok := t.(*Field); ok {
-		log.Fatalf("field Type %s cannot change to Expr", t)
+		errutil.Throwf("field Type %s cannot change to Expr", t)
 		return nil
 	}
 	if et, ok := t.(*exprType); ok {
@@ -517,6 +616,11 @@ func TypeInitValue(t Type, file *File) ast.Expr {
 		return &ast.CompositeLit{
 			Type: TypeExprInFile(t, file),
 		}
+	case *chanType:
+		return &ast.CallExpr{
+			Fun:  ast.NewIdent("make"),
+			Args: []ast.Expr{TypeExprInFile(t, file)},
+		}
 	case *PointerType:
 		if tm.Stars > 1 {
 			return ast.NewIdent("nil")
@@ -531,6 +635,8 @@ func TypeInitValue(t Type, file *File) ast.Expr {
 		}
 	case *FuncType, *InterfaceType:
 		return ast.NewIdent("nil")
+	case *TypeParam:
+		return typeParamZeroValue(tm)
 	case *namedType, *aliasType:
 		log.Printf("type: %s", t)
 		if basic, ok := tm.Underlying().(*BasicType); ok {
@@ -546,10 +652,20 @@ func TypeInitValue(t Type, file *File) ast.Expr {
 		// case *StructType:
 	}
 
-	log.Fatalf("donot support init value for type: %s", t)
+	errutil.Throwf("donot support init value for type: %s", t)
 	return nil
 }
 
+// typeParamZeroValue returns *new(T), the zero value of any type argument
+func typeParamZeroValue(t *TypeParam) ast.Expr {
+	return &ast.StarExpr{
+		X: &ast.CallExpr{
+			Fun:  ast.NewIdent("new"),
+			Args: []ast.Expr{ast.NewIdent(t.name)},
+		},
+	}
+}
+
 func NotNilPointerValue(t *PointerType, file *File) ast.Expr {
 	if t.Stars != 1 {
 		return nil
@@ -595,7 +711,7 @@ func ExprToString(expr ast.Expr) string {
 	case *ast.Ident:
 		return v.Name
 	case *ast.Ellipsis:
-		return "..."
+		return "..." + ExprToString(v.Elt)
 	case *ast.BasicLit:
 		return v.Value
 	case *ast.FuncLit:
@@ -620,6 +736,12 @@ func ExprToString(expr ast.Expr) string {
 		return fmt.Sprintf("%s.%s", ExprToString(v.X), ExprToString(v.Sel))
 	case *ast.IndexExpr:
 		return fmt.Sprintf("%s[%s]", ExprToString(v.X), ExprToString(v.Index))
+	case *ast.IndexListExpr:
+		indices := []string{}
+		for _, index := range v.Indices {
+			indices = append(indices, ExprToString(index))
+		}
+		return fmt.Sprintf("%s[%s]", ExprToString(v.X), strings.Join(indices, ","))
 	case *ast.SliceExpr:
 		if v.Slice3 {
 			return fmt.Sprintf("%s[%s:%s:%s]", ExprToString(v.X), ExprToString(v.Low), ExprToString(v.High), ExprToString(v.Max))
@@ -671,11 +793,13 @@ func ExprToString(expr ast.Expr) string {
 	case *ast.MapType:
 		return fmt.Sprintf("map[%s]%s", ExprToString(v.Key), ExprToString(v.Value))
 	case *ast.ChanType:
-		if v.Dir == ast.SEND {
-			return fmt.Sprintf("->%s", ExprToString(v.Value))
-		} else {
-			return fmt.Sprintf("<-%s", ExprToString(v.Value))
+		switch v.Dir {
+		case ast.SEND:
+			return fmt.Sprintf("chan<- %s", ExprToString(v.Value))
+		case ast.RECV:
+			return fmt.Sprintf("<-chan %s", ExprToString(v.Value))
 		}
+		return fmt.Sprintf("chan %s", ExprToString(v.Value))
 	}
 
 	return ""
@@ -694,86 +818,138 @@ func ExprToString(expr ast.Expr) string {
 
  */
 
-func ParseType(node ast.Node) Type {
+// ParseType parses the type of node without any scope
+func ParseType(node ast.Node) (typ Type, err error) {
+	defer errutil.Catch(&err)
+	return parseType(node), nil
+}
+
+func parseType(node ast.Node) Type {
 	var typ Type
 
 	switch ident := node.(type) {
 	//Exprs:
 	case *ast.BadExpr:
-		log.Fatalf("cannot support BadExpr")
+		errutil.Throw(unsupportedType(node, "BadExpr"))
 	case *ast.Ident:
 		typ = NewType(ident.Name)
 	case *ast.Ellipsis:
-		log.Fatalf("cannot support Ellipsis")
+		typ = TypeWithSlice(parseType(ident.Elt))
 	case *ast.BasicLit:
-		log.Fatalf("cannot support BasicLit")
+		errutil.Throw(unsupportedType(node, "BasicLit"))
 	case *ast.FuncLit:
-		log.Fatalf("cannot support FuncLit")
+		errutil.Throw(unsupportedType(node, "FuncLit"))
 	case *ast.CompositeLit:
-		typ = ParseType(ident.Type)
+		typ = parseType(ident.Type)
 	case *ast.ParenExpr:
-		log.Fatalf("cannot support ParenExpr")
+		errutil.Throw(unsupportedType(node, "ParenExpr"))
 	case *ast.SelectorExpr:
-		typ = ParseType(ident.Sel)
+		typ = parseType(ident.Sel)
 		if _, ok := ident.X.(*ast.Ident); ok {
 			// typ.SetPackageName(ni.Name)
 		} else {
-			printutil.FatalNodef(node, "cannot support parse type:")
+			errutil.Throw(unsupportedType(node, "nested SelectorExpr"))
 		}
 	case *ast.IndexExpr:
-		log.Fatalf("cannot support IndexExpr")
+		typ = parseGenericType(ident.X, []ast.Expr{ident.Index})
+	case *ast.IndexListExpr:
+		typ = parseGenericType(ident.X, ident.Indices)
 	case *ast.SliceExpr:
-		log.Fatalf("cannot support SliceExpr")
+		errutil.Throw(unsupportedType(node, "SliceExpr"))
 	case *ast.TypeAssertExpr:
-		log.Fatalf("cannot support TypeAssertExpr")
+		errutil.Throw(unsupportedType(node, "TypeAssertExpr"))
 	case *ast.CallExpr:
-		log.Fatalf("cannot support CallExpr")
+		errutil.Throw(unsupportedType(node, "CallExpr"))
 	case *ast.StarExpr:
-		typ2 := ParseType(ident.X)
+		typ2 := parseType(ident.X)
 		typ = TypeWithPointer(typ2)
 	case *ast.UnaryExpr:
-		typ = ParseType(ident.X)
+		typ = parseType(ident.X)
 		if ident.Op == token.MUL || ident.Op == token.AND {
 			typ = TypeWithPointer(typ)
 		}
 	case *ast.BinaryExpr:
-		log.Fatalf("cannot support BinaryExpr")
+		errutil.Throw(unsupportedType(node, "BinaryExpr"))
 	case *ast.KeyValueExpr:
-		log.Fatalf("cannot support KeyValueExpr")
+		errutil.Throw(unsupportedType(node, "KeyValueExpr"))
 	case *ast.ArrayType:
-		typ = ParseType(ident.Elt)
+		typ = parseType(ident.Elt)
 		typ = TypeWithSlice(typ)
 		//Types:
 	case *ast.StructType:
-		log.Fatalf("cannot support StructType")
+		errutil.Throw(unsupportedType(node, "StructType"))
 	case *ast.FuncType:
-		log.Fatalf("cannot support FuncType")
+		typ = parseFuncType(ident)
 	case *ast.InterfaceType:
 		typ = NewType("interface")
 	case *ast.MapType:
-		key := ParseType(ident.Key)
-		val := ParseType(ident.Value)
+		key := parseType(ident.Key)
+		val := parseType(ident.Value)
 		typ = MapType(key, val)
 	case *ast.ChanType:
-		log.Fatalf("cannot support ChanType")
+		typ = ChanType(parseType(ident.Value), ident.Dir)
 
 		//others:
 	case *ast.TypeSpec:
-		typ = TypeWithName(ParseType(ident.Type), ident.Name.Name)
+		nt := TypeWithName(parseType(ident.Type), ident.Name.Name).(*namedType)
+		if ident.TypeParams != nil {
+			nt.params = typeParamList(ident.TypeParams)
+		}
+		typ = nt
 	case *ast.ValueSpec:
 		if ident.Type != nil {
-			typ = ParseType(ident.Type)
+			typ = parseType(ident.Type)
 		} else {
-			typ = ParseType(ident.Values[0])
+			typ = parseType(ident.Values[0])
 		}
 	default:
-		printutil.FatalNodef(node, "ParseType donnot Support:")
+		errutil.Throw(unsupportedType(node, fmt.Sprintf("%T", node)))
 	}
 
 	// ast.Print(token.NewFileSet(), expr)
 	return typ
 }
 
+func parseFuncType(fnType *ast.FuncType) *FuncType {
+	parseFields := func(list *ast.FieldList) []*Field {
+		fields := []*Field{}
+		if list == nil {
+			return fields
+		}
+		for _, item := range list.List {
+			if len(item.Names) == 0 {
+				fields = append(fields, NewField(parseType(item.Type), "", ""))
+			}
+			for _, name := range item.Names {
+				fields = append(fields, NewField(parseType(item.Type), name.Name, ""))
+			}
+		}
+		return fields
+	}
+
+	ft := &FuncType{
+		Params:  parseFields(fnType.Params),
+		Results: parseFields(fnType.Results),
+	}
+	if params := fnType.Params; params != nil && len(params.List) > 0 {
+		_, ft.Variadic = params.List[len(params.List)-1].Type.(*ast.Ellipsis)
+	}
+	return ft
+}
+
+// parseGenericType parses the instantiated generic type, like Page[User]
+func parseGenericType(x ast.Expr, indices []ast.Expr) Type {
+	nt, ok := parseType(x).(*namedType)
+	if !ok {
+		errutil.Throw(unsupportedType(x, "generic type"))
+	}
+	for i, index := range indices {
+		nt.params = append(nt.params, &TypeParam{name: fmt.Sprintf("T%d", i)})
+		nt.args = append(nt.args, parseType(index))
+	}
+	return nt
+}
+
 func IsErrorType(t Type) bool {
 	if x, ok := t.(*namedType); ok {
 		return x.name == "error"
@@ -781,11 +957,103 @@ func IsErrorType(t Type) bool {
 	return false
 }
 
-func ParseTypeString(str string) Type {
+// IsFuncType reports whether t is a func, which can be compared only to nil
+func IsFuncType(t Type) bool {
+	_, ok := t.Underlying().(*FuncType)
+	return ok
+}
+
+// Methods returns the method declarations of the named type t or *t in the files of its package,
+// in the order they are declared
+func Methods(t Type) []*ast.FuncDecl {
+	named, file := namedOf(t)
+	if named == nil || file == nil || file.BelongTo == nil {
+		return nil
+	}
+
+	methods := []*ast.FuncDecl{}
+	for _, f := range file.BelongTo.Files {
+		for _, decl := range f.File.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+				continue
+			}
+			if receiverName(fd.Recv.List[0].Type) == named.name {
+				methods = append(methods, fd)
+			}
+		}
+	}
+	return methods
+}
+
+// TypePosition returns where the named type of t or *t is declared, invalid if it is unknown
+func TypePosition(t Type) token.Position {
+	named, _ := namedOf(t)
+	if named == nil {
+		return token.Position{}
+	}
+	return named.pos
+}
+
+// namedOf returns the named type of t or *t, and the file it is declared in
+func namedOf(t Type) (*namedType, *File) {
+	var file *File
+	for {
+		switch x := t.(type) {
+		case *namedType:
+			if file == nil {
+				file = x.File()
+			}
+			return x, file
+		case *PointerType:
+			if x.Stars != 1 {
+				return nil, nil
+			}
+			t = x.Base
+		case *filedType:
+			file = x.file
+			t = x.Type
+		case *exprType:
+			t = x.Type
+		default:
+			return nil, nil
+		}
+	}
+}
+
+// receiverName returns the type name of a receiver, e.g. Page of *Page[T]
+func receiverName(expr ast.Expr) string {
+	for {
+		switch x := expr.(type) {
+		case *ast.Ident:
+			return x.Name
+		case *ast.StarExpr:
+			expr = x.X
+		case *ast.ParenExpr:
+			expr = x.X
+		case *ast.IndexExpr:
+			expr = x.X
+		case *ast.IndexListExpr:
+			expr = x.X
+		default:
+			return ""
+		}
+	}
+}
+
+func ParseTypeString(str string) (Type, error) {
 	expr, err := ParseExpr(str)
 	if err != nil {
-		log.Fatalf("parse type %s error: %v", str, err)
+		return nil, errutil.New("parse type error: %v", err).WithExpr(str)
 	}
 
 	return ParseType(expr)
 }
+
+func unsupportedType(node ast.Node, what string) *errutil.Error {
+	e := errutil.New("cannot support %s", what)
+	if expr, ok := node.(ast.Expr); ok {
+		e.WithExpr(ExprToString(expr))
+	}
+	return e
+}
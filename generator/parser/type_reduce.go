@@ -5,6 +5,7 @@ import (
 	"go/token"
 	"log"
 
+	"github.com/lawrsp/pigo/generator/errutil"
 	"github.com/lawrsp/pigo/generator/printutil"
 )
 
@@ -61,10 +62,18 @@ func (file *File) LookupImport(name string) (*File, *ast.Object) {
 	if imptPath, ok := file.FindImportPath(name); ok {
 		impt := file.FindImport(name)
 		if impt == nil {
-			impt = file.parser.ImportPackage(name, imptPath, file.BelongTo.Dir)
-			if impt != nil {
-				file.AddImport(name, impt)
+			var err error
+			impt, err = file.parser.ImportPackage(name, imptPath, file.BelongTo.Dir)
+			if err != nil {
+				var spec ast.Node
+				for _, ispc := range file.File.Imports {
+					if nameOfImport(ispc) == name {
+						spec = ispc
+					}
+				}
+				errutil.Throw(file.errorf(spec, "%w", err))
 			}
+			file.AddImport(name, impt)
 		}
 		if impt != nil {
 			obj := ast.NewObj(ast.Pkg, name)
@@ -102,46 +111,76 @@ func (file *File) lookup(name string) (*File, *ast.Object) {
 
 func funcTypeFromFuncType(file *File, fnType *ast.FuncType) *FuncType {
 	ft := &FuncType{}
-	paramList := []*Field{}
-	if fnType.Params != nil && fnType.Params.List != nil && len(fnType.Params.List) > 0 {
-		for _, param := range fnType.Params.List {
-			paramType := &Field{}
-			if param.Names != nil {
-				paramType.SetName(param.Names[0].Name)
+	ft.Params = fieldsFromFieldList(file, fnType.Params)
+	ft.Results = fieldsFromFieldList(file, fnType.Results)
+	if params := fnType.Params; params != nil && len(params.List) > 0 {
+		//...T
+		_, ft.Variadic = params.List[len(params.List)-1].Type.(*ast.Ellipsis)
+	}
+	return ft
+}
+
+// fieldsFromFieldList reduces the params or results, one field for each name, like a, b int
+func fieldsFromFieldList(file *File, list *ast.FieldList) []*Field {
+	fields := []*Field{}
+	if list == nil {
+		return fields
+	}
+	for _, item := range list.List {
+		names := []string{""}
+		if len(item.Names) > 0 {
+			names = nil
+			for _, name := range item.Names {
+				names = append(names, name.Name)
 			}
-			paramType.SetType(file.ReduceType(param.Type))
-			//NewUnknownType(file, param.Type))
-			paramList = append(paramList, paramType)
 		}
-	}
-	resultList := []*Field{}
-	if fnType.Results != nil && fnType.Results.List != nil && len(fnType.Results.List) > 0 {
-		for _, result := range fnType.Results.List {
-			resType := &Field{}
-			if result.Names != nil {
-				resType.SetName(result.Names[0].Name)
+		t := file.ReduceType(item.Type)
+		for i, name := range names {
+			field := &Field{}
+			field.SetName(name)
+			if i > 0 && t != nil {
+				field.SetType(t.Copy())
+			} else {
+				field.SetType(t)
 			}
-			resType.SetType(file.ReduceType(result.Type))
-			//NewUnknownType(file, result.Type))
-			resultList = append(resultList, resType)
+			fields = append(fields, field)
 		}
 	}
-	ft.Params = paramList
-	ft.Results = resultList
-	return ft
+	return fields
 }
 
-func funcTypeFromFuncDecl(file *File, decl *ast.FuncDecl) Type {
+// funcTypeFromFuncDecl reduces the func declaration,
+// the type parameters of a generic receiver are bound to recvArgs, or to themselves if recvArgs is nil
+func funcTypeFromFuncDecl(file *File, decl *ast.FuncDecl, recvArgs []Type) Type {
 	name := decl.Name.Name
+	if decl.Recv != nil && len(decl.Recv.List) > 0 {
+		if names := receiverTypeParams(decl.Recv.List[0].Type); len(names) > 0 {
+			if len(recvArgs) != len(names) {
+				//the receiver is another type
+				recvArgs = make([]Type, len(names))
+				for i, name := range names {
+					recvArgs[i] = &TypeParam{name: name}
+				}
+			}
+			file = file.withTypeArgs(names, recvArgs)
+		}
+	}
+	var params []*TypeParam
+	if decl.Type.TypeParams != nil {
+		params = typeParamList(decl.Type.TypeParams)
+		file = file.withTypeArgs(typeParamNames(params), typeParamsAsArgs(params))
+	}
+
 	fnt := funcTypeFromFuncType(file, decl.Type)
 	ft := fnt.Underlying().(*FuncType)
+	ft.TypeParams = params
 	if decl.Recv != nil {
 		ft.Receiver = &Field{}
 		if decl.Recv.List[0].Names != nil {
 			ft.Receiver.SetName(decl.Recv.List[0].Names[0].Name)
 		}
 		if len(decl.Recv.List) > 1 {
-			log.Fatalf("receiver list == %d", len(decl.Recv.List))
+			errutil.Throw(file.errorf(decl.Recv, "receiver list == %d", len(decl.Recv.List)))
 		}
 		// printutil.PrintNodef(decl.Recv.List[0].Type, "%s decl.Recv.List[0].Type:", name)
 		// ft.receiver.Type = NewUnknownType(decl.Recv.List[0].Type)
@@ -171,7 +210,7 @@ func preDotType(t Type) (*File, Type) {
 			return file, utt
 		case *FuncType:
 			if len(utt.Results) != 1 {
-				log.Fatalf("cannot decide cause function result is not 1")
+				errutil.Throwf("cannot decide %s cause function result is not 1", t)
 			}
 			ResolveUnknownField(utt.Results[0])
 			res = utt.Results[0].Type
@@ -230,7 +269,7 @@ func (file *File) tryTypeMethod(res Type, name string) Type {
 	for i, f := range dfiles {
 		// printutil.PrintNodef(decl, "func %s", t.Sel.Name)
 		// log.Printf("file %s fined func %s", resfile.Name, t.Sel.Name)
-		nt := funcTypeFromFuncDecl(f, decls[i])
+		nt := funcTypeFromFuncDecl(f, decls[i], TypeArgs(res))
 		ft := nt.Underlying().(*FuncType)
 		ResolveUnknownField(ft.Receiver)
 		// log.Printf("%s === %s ? %v", ft.Receiver.Type, res, TypeEqualAsReceiver(ft.Receiver.Type, res))
@@ -262,9 +301,14 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 	switch t := expr.(type) {
 	//Exprs:
 	case *ast.BadExpr:
-		log.Fatalf("cannot support BadExpr")
+		errutil.Throw(file.errorf(t, "cannot support BadExpr"))
 	case *ast.Ident:
 		name := t.Name
+		if file != nil {
+			if arg, ok := file.typeArgs[name]; ok {
+				return arg
+			}
+		}
 		if try := Universe.Lookup(name); try != nil {
 			return try
 		}
@@ -286,14 +330,27 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 				return TypeWithFile(at, nf)
 			} else {
 				// nt := &namedType{name: name, file: nf}
-				underlying := nf.ReduceType(spec.Type)
+				var underlying Type
 				// if t, ok := underlying.(*filedType); ok {
 				//	log.Printf("filetype=======")
 				//	if _, ok := t.Type.(*StructType); ok {
 				//		log.Printf("structType========")
 				//	}
 				// }
-				nt := TypeWithFile(TypeWithName(underlying, name), nf)
+				named := &namedType{name: name, obj: nf.typeObject(spec)}
+				if nf.contains(spec.Pos()) {
+					named.pos = nf.parser.Position(spec.Pos())
+				}
+				if spec.TypeParams != nil {
+					//generic type, the type parameters are not instantiated
+					named.spec = spec
+					named.params = typeParamList(spec.TypeParams)
+					underlying = nf.withTypeArgs(typeParamNames(named.params), typeParamsAsArgs(named.params)).ReduceType(spec.Type)
+				} else {
+					underlying = nf.ReduceType(spec.Type)
+				}
+				named.SetUnderlying(underlying)
+				nt := TypeWithFile(named, nf)
 				return nt
 			}
 		case ast.Var:
@@ -308,7 +365,7 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 		case ast.Fun:
 			//ast.FuncSepc
 			// log.Printf("fun object %s", name)
-			ft := funcTypeFromFuncDecl(nf, obj.Decl.(*ast.FuncDecl))
+			ft := funcTypeFromFuncDecl(nf, obj.Decl.(*ast.FuncDecl), nil)
 			return TypeWithFile(ft, nf)
 		case ast.Pkg:
 			//ast.Scope
@@ -317,27 +374,31 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 			pt := &packageType{name: name, path: impt.Path, file: nf}
 			return pt
 		default:
-			log.Fatalf("unsupported type of object %s", name)
+			errutil.Throw(file.errorf(t, "unsupported type of object %s", name))
 		}
 
 	case *ast.Ellipsis:
-		log.Fatalln("cannot support Ellipsis")
-		return nil
+		//...T of a variadic param is []T
+		elt := file.ReduceType(t.Elt)
+		if elt == nil {
+			errutil.Throw(file.errorf(t.Elt, "cannot reduce type"))
+		}
+		return TypeWithFile(TypeWithSlice(elt), elt.File())
 	case *ast.BasicLit:
-		log.Fatalf("cannot support BasicLit")
+		errutil.Throw(file.errorf(t, "cannot support BasicLit"))
 	case *ast.FuncLit:
-		log.Fatalf("cannot support FuncLit")
+		errutil.Throw(file.errorf(t, "cannot support FuncLit"))
 	case *ast.CompositeLit:
 		//Type{}  will reduced by the Type
 		return file.ReduceType(t.Type)
 	case *ast.ParenExpr:
-		log.Fatalf("cannot support ParenExpr")
+		errutil.Throw(file.errorf(t, "cannot support ParenExpr"))
 	case *ast.SelectorExpr:
 		//X.Sel
 		//@TODO:
 		xt := file.ReduceType(t.X)
 		if xt == nil {
-			log.Fatalf("reduct type(%v) failed", t.X)
+			errutil.Throw(file.errorf(t.X, "cannot reduce type"))
 		}
 		resfile, res := preDotType(xt)
 		if res == nil {
@@ -364,6 +425,13 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 	case *ast.IndexExpr:
 		//x[Index]
 		res := file.ReduceType(t.X)
+		if res == nil {
+			errutil.Throw(file.errorf(t.X, "cannot reduce type"))
+		}
+		if isGeneric(res) {
+			//Generic[T]
+			return file.instantiate(res, []ast.Expr{t.Index}, t)
+		}
 		switch ut := res.Underlying().(type) {
 		case *ArrayType:
 			switch t.Index.(type) {
@@ -374,7 +442,7 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 				//x[1:2]
 				return TypeWithFile(TypeWithSlice(TypeSkipBracket(ut, 1)), res.File())
 			default:
-				printutil.FatalNodef(t.Index, "index expr error:")
+				errutil.Throw(file.errorf(t.Index, "index expr error"))
 			}
 		// case *sliceType:
 		// 	switch t.Index.(type) {
@@ -392,35 +460,43 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 		case *mapType:
 			return ut.val
 		default:
-			log.Fatalf("type %s donot suppoort index", ut)
+			errutil.Throw(file.errorf(t, "type %s donot suppoort index", ut))
 			return nil
 		}
 
+	case *ast.IndexListExpr:
+		//Generic[K, V]
+		res := file.ReduceType(t.X)
+		if res == nil {
+			errutil.Throw(file.errorf(t.X, "cannot reduce type"))
+		}
+		return file.instantiate(res, t.Indices, t)
+
 	case *ast.SliceExpr:
 		//[]X
 		res := file.ReduceType(t.X)
 		return TypeWithFile(TypeWithSlice(res), res.File())
 
 	case *ast.TypeAssertExpr:
-		log.Fatalf("cannot support TypeAssertExpr")
+		errutil.Throw(file.errorf(t, "cannot support TypeAssertExpr"))
 	case *ast.CallExpr:
 		res := file.ReduceType(t.Fun)
 		if ft, ok := res.Underlying().(*FuncType); ok {
 			if ft.Results == nil || len(ft.Results) != 1 {
 				//parent ?
-				log.Fatalf("results != 1")
+				errutil.Throw(file.errorf(t, "cannot decide the type of call, results != 1"))
 				return NewUnknownType(res.File(), t.Fun)
 			}
 			ResolveUnknownField(ft.Results[0])
 			return ft.Results[0].Type
 		}
 
-		log.Fatalf("cannot support CallExpr")
+		errutil.Throw(file.errorf(t, "cannot support CallExpr"))
 	case *ast.StarExpr:
 		//*X
 		res := file.ReduceType(t.X)
 		if res == nil {
-			log.Fatalf("cannot reduce type: %v", ExprToString(t.X))
+			errutil.Throw(file.errorf(t.X, "cannot reduce type"))
 		}
 		// printutil.PrintNodef(t.X, "")
 		return TypeWithFile(TypeWithPointer(res), res.File())
@@ -432,15 +508,15 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 	case *ast.UnaryExpr:
 		//*X  &X
 		if t.Op != token.MUL && t.Op != token.AND {
-			printutil.FatalNodef(t, "cannot support UnaryExpr:")
+			errutil.Throw(file.errorf(t, "cannot support UnaryExpr %s", t.Op))
 		}
 
 		res := file.ReduceType(t.X)
 		return TypeWithFile(TypeWithPointer(res), res.File())
 	case *ast.BinaryExpr:
-		log.Fatalf("cannot support BinaryExpr")
+		errutil.Throw(file.errorf(t, "cannot support BinaryExpr"))
 	case *ast.KeyValueExpr:
-		log.Fatalf("cannot support KeyValueExpr")
+		errutil.Throw(file.errorf(t, "cannot support KeyValueExpr"))
 
 		//Types:
 	case *ast.ArrayType:
@@ -463,6 +539,9 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 				field.SetName(fd.Names[0].Name)
 			}
 			field.SetType(NewUnknownType(file, fd.Type))
+			if file.contains(fd.Pos()) {
+				field.pos = file.parser.Position(fd.Pos())
+			}
 			if fd.Tag != nil {
 				if len := len(fd.Tag.Value); len > 2 {
 					field.Tag = fd.Tag.Value[1 : len-1]
@@ -505,17 +584,73 @@ func (file *File) ReduceType(expr ast.Expr) Type {
 		return TypeWithFile(&mapType{key, val}, file)
 
 	case *ast.ChanType:
-		log.Fatalf("cannot support ChanType")
+		elem := file.ReduceType(t.Value)
+		if elem == nil {
+			errutil.Throw(file.errorf(t.Value, "cannot reduce type"))
+		}
+		return TypeWithFile(ChanType(elem, t.Dir), file)
 
 	case nil:
 		return nil
 	default:
-		printutil.FatalNodef(expr, "New expr in go")
+		errutil.Throw(file.errorf(expr, "cannot support %T", expr))
 	}
 
 	return nil
 }
 
+// instantiate reduces the type arguments in the file, and instantiates the generic type with them
+func (file *File) instantiate(generic Type, indices []ast.Expr, node ast.Expr) Type {
+	ft, _ := generic.(*filedType)
+	var named *namedType
+	if ft != nil {
+		named, _ = ft.Type.(*namedType)
+	}
+	if named == nil || named.spec == nil {
+		errutil.Throw(file.errorf(node, "type %s is not generic", generic))
+	}
+	if named.args != nil {
+		errutil.Throw(file.errorf(node, "type %s is instantiated already", generic))
+	}
+	if len(indices) != len(named.params) {
+		errutil.Throw(file.errorf(node, "type %s has %d type parameters, but %d type arguments", generic, len(named.params), len(indices)))
+	}
+
+	args := make([]Type, len(indices))
+	for i, index := range indices {
+		args[i] = file.ReduceType(index)
+		if args[i] == nil {
+			errutil.Throw(file.errorf(index, "cannot reduce type"))
+		}
+	}
+
+	nf := ft.file.withTypeArgs(typeParamNames(named.params), args)
+	inst := &namedType{name: named.name, obj: named.obj, params: named.params, args: args, spec: named.spec, pos: named.pos}
+	inst.SetUnderlying(nf.ReduceType(named.spec.Type))
+	return TypeWithFile(inst, ft.file)
+}
+
+// receiverTypeParams returns the names of the type parameters of a receiver, like *Page[T]
+func receiverTypeParams(expr ast.Expr) []string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	var indices []ast.Expr
+	switch x := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{x.Index}
+	case *ast.IndexListExpr:
+		indices = x.Indices
+	}
+	names := []string{}
+	for _, index := range indices {
+		if ident, ok := index.(*ast.Ident); ok {
+			names = append(names, ident.Name)
+		}
+	}
+	return names
+}
+
 func (pkg *Package) ReduceType(expr ast.Expr) (*File, Type) {
 	for _, file := range pkg.Files {
 		t := file.ReduceType(expr)
@@ -1,8 +1,12 @@
 package parser
 
 import (
+	"io/ioutil"
 	"log"
+	"os"
 	"testing"
+
+	"github.com/lawrsp/pigo/generator/errutil"
 )
 
 type TT int
@@ -123,3 +127,169 @@ func TestTypeAssignable(t *testing.T) {
 	}
 
 }
+
+func TestGenericType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"g.go": `package g
+
+type Page[T any] struct {
+	Items []T
+	Total int
+}
+
+func (p *Page[T]) First() T {
+	return p.Items[0]
+}
+
+type Pair[K comparable, V any] struct {
+	Key K
+	Val V
+}
+
+type User struct {
+	Name string
+}
+
+type List struct {
+	Pages []Page[User]
+}
+`,
+	})
+
+	p := NewParser()
+	pkg, err := p.ParsePackageDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := pkg.Files[0]
+	reduce := func(src string) (typ Type, err error) {
+		defer errutil.Catch(&err)
+		return file.ReduceTypeSrc(src), nil
+	}
+	exprOf := func(typ Type) string {
+		return ExprToString(TypeExprInFile(typ, file))
+	}
+
+	page, err := reduce("Page")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, len(TypeParams(page)), 1)
+	expect(t.Errorf, exprOf(page), "Page[T]")
+
+	inst, err := reduce("Page[User]")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, inst.String(), "g.Page[g.User]")
+	expect(t.Errorf, exprOf(inst), "Page[User]")
+	items := inst.Underlying().(*StructType).Fields[0]
+	ResolveUnknownField(items)
+	expect(t.Errorf, items.Type.String(), "[]g.User")
+	assert(t, !TypeEqual(inst, page), "%s != %s", inst, page)
+
+	pages, err := reduce("List{}.Pages")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, exprOf(pages), "[]Page[User]")
+	assert(t, TypeEqual(TypeSkipBracket(pages, 1), inst), "%s == %s", pages, inst)
+
+	first, err := reduce("Page[User]{}.First()")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, first.String(), "g.User")
+
+	methods := Methods(TypeWithPointer(inst))
+	expect(t.Errorf, len(methods), 1)
+	expect(t.Errorf, methods[0].Name.Name, "First")
+	user, err := reduce("User")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, len(Methods(user)), 0)
+
+	pair, err := reduce("Pair[string, *User]")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, exprOf(pair), "Pair[string,*User]")
+	expect(t.Errorf, len(TypeArgs(pair)), 2)
+
+	_, err = reduce("Pair[User]")
+	assert(t, err != nil, "type arguments should be checked")
+	_, err = reduce("User[int]")
+	assert(t, err != nil, "User is not generic")
+}
+
+func TestChanFuncType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"c.go": `package c
+
+type Event struct {
+	Name string
+}
+
+type Hub struct {
+	In     chan Event
+	Out    <-chan *Event
+	Done   chan<- struct{}
+	Recv   <-chan Event
+	OnSave func(a, b int) error
+	OnLoad func(x, y int) error
+}
+
+func Send(name string, events ...Event) error {
+	return nil
+}
+`,
+	})
+
+	p := NewParser()
+	pkg, err := p.ParsePackageDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := pkg.Files[0]
+	reduce := func(src string) (typ Type, err error) {
+		defer errutil.Catch(&err)
+		return file.ReduceTypeSrc(src), nil
+	}
+	exprOf := func(typ Type) string {
+		return ExprToString(TypeExprInFile(typ, file))
+	}
+
+	in, err := reduce("Hub{}.In")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, in.String(), "chan c.Event")
+	expect(t.Errorf, exprOf(in), "chan Event")
+
+	out, err := reduce("Hub{}.Out")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, exprOf(out), "<-chan *Event")
+	assert(t, !TypeEqual(in, out), "%s != %s", in, out)
+
+	recv, err := reduce("Hub{}.Recv")
+	expect(t.Errorf, err, nil)
+	assert(t, TypeAssignable(recv, in), "%s assignable to %s", in, recv)
+	assert(t, !TypeAssignable(in, recv), "%s not assignable to %s", recv, in)
+
+	onSave, err := reduce("Hub{}.OnSave")
+	expect(t.Errorf, err, nil)
+	expect(t.Errorf, onSave.String(), "(int,int)error")
+	fn := onSave.Underlying().(*FuncType)
+	expect(t.Errorf, len(fn.Params), 2)
+
+	same, err := reduce("Hub{}.OnLoad")
+	expect(t.Errorf, err, nil)
+	assert(t, TypeEqual(onSave, same), "%s == %s", onSave, same)
+
+	send, err := reduce("Send")
+	expect(t.Errorf, err, nil)
+	sendFn := send.Underlying().(*FuncType)
+	assert(t, sendFn.Variadic, "Send is variadic")
+	expect(t.Errorf, len(sendFn.Params), 2)
+	expect(t.Errorf, sendFn.String(), "(string,...c.Event)error")
+	expect(t.Errorf, exprOf(sendFn.Params[1].Type), "[]Event")
+}
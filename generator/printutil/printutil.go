@@ -7,13 +7,6 @@ import (
 	"log"
 )
 
-func FatalNodef(node interface{}, format string, args ...interface{}) {
-	var buf bytes.Buffer
-	_ = ast.Fprint(&buf, token.NewFileSet(), node, ast.NotNilFilter)
-	log.Printf(format, args...)
-	log.Fatalf("%s", buf.String())
-}
-
 func PrintNodef(node interface{}, format string, args ...interface{}) {
 	var buf bytes.Buffer
 	_ = ast.Fprint(&buf, token.NewFileSet(), node, ast.NotNilFilter)
@@ -0,0 +1,176 @@
+// Package testutil runs the generators on the golden cases in testdata
+//
+// a case is a directory with the input package, the task config and the expected outputs:
+//
+//	testdata/withmap/model.go
+//	testdata/withmap/task.yaml
+//	testdata/withmap/zz_setter.go.golden
+//
+// run the tests with -update to write the golden files
+package testutil
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/configutil"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const (
+	// ConfigName is the task config of a case, in the format of the generator
+	ConfigName = "task.yaml"
+	// GoldenSuffix is added to the output name for the expected output
+	GoldenSuffix = ".golden"
+)
+
+// Case is a golden case, the generator should parse the package in Dir with Parser,
+// and keep the results in Outputs, so nothing is written
+type Case struct {
+	Name    string
+	Dir     string
+	Parser  *parser.Parser
+	Outputs *generator.Outputs
+}
+
+// ReadConfig reads the task config of the case
+func (c *Case) ReadConfig(config interface{}) error {
+	return configutil.ReadConfig(filepath.Join(c.Dir, ConfigName), config)
+}
+
+// Path returns the path of name in the case dir
+func (c *Case) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(c.Dir, name)
+}
+
+// GenerateFunc runs the generator of the case
+type GenerateFunc func(c *Case) error
+
+// Run runs every case in the subdirectories of dir as a subtest
+func Run(t *testing.T, dir string, generate GenerateFunc) {
+	t.Helper()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		caseDir := filepath.Join(dir, entry.Name())
+		t.Run(entry.Name(), func(t *testing.T) {
+			RunCase(t, caseDir, generate)
+		})
+	}
+}
+
+// RunCase runs the case in dir, the outputs are compared with the golden files,
+// and type checked with the package they are in
+func RunCase(t *testing.T, dir string, generate GenerateFunc) {
+	t.Helper()
+	c := &Case{
+		Name:    filepath.Base(dir),
+		Dir:     dir,
+		Parser:  parser.NewParser(),
+		Outputs: &generator.Outputs{},
+	}
+	if err := generate(c); err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	outputs := c.Outputs.Files()
+	if len(outputs) == 0 {
+		t.Fatal("generate: no output")
+	}
+
+	generated := map[string]bool{}
+	for _, output := range generator.SortedKeys(outputs) {
+		name, err := filepath.Rel(dir, output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		golden := filepath.Join(dir, name+GoldenSuffix)
+		generated[golden] = true
+		result := outputs[output]
+
+		if *update {
+			if err := ioutil.WriteFile(golden, result, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		expect, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Errorf("%v, run with -update to create it", err)
+			continue
+		}
+		name = filepath.ToSlash(name)
+		if diff := generator.UnifiedDiff("golden/"+name, "generated/"+name, expect, result); diff != "" {
+			t.Errorf("%s is not the golden one, run with -update to accept it:\n%s", name, diff)
+		}
+	}
+
+	goldens, err := filepath.Glob(filepath.Join(dir, "*"+GoldenSuffix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, golden := range goldens {
+		if !generated[golden] {
+			t.Errorf("%s is not generated", golden)
+		}
+	}
+
+	if err := TypeCheck(outputs); err != nil {
+		t.Error(err)
+	}
+}
+
+// TypeCheck type checks the outputs with the packages they are in,
+// the outputs replace the files of the same names
+func TypeCheck(outputs map[string][]byte) error {
+	dirs := map[string]map[string][]byte{}
+	for output, content := range outputs {
+		abs, err := filepath.Abs(output)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(abs)
+		if dirs[dir] == nil {
+			dirs[dir] = map[string][]byte{}
+		}
+		dirs[dir][abs] = content
+	}
+
+	for _, dir := range generator.SortedKeys(dirs) {
+		if err := checkPackage(dir, dirs[dir]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkPackage(dir string, overlay map[string][]byte) error {
+	typeErrors, err := generator.TypeCheck(&build.Default, dir, overlay)
+	if err != nil {
+		return err
+	}
+	if len(typeErrors) > 0 {
+		errs := []string{}
+		for _, typeErr := range typeErrors {
+			errs = append(errs, typeErr.Error())
+		}
+		return fmt.Errorf("type check %s:\n%s", dir, strings.Join(errs, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package testutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeConfig struct {
+	Type   string
+	Output string
+}
+
+// generateDouble generates a method of the type in the config
+func generateDouble(c *Case) error {
+	conf := &fakeConfig{}
+	if err := c.ReadConfig(conf); err != nil {
+		return err
+	}
+	pkg, err := c.Parser.ParsePackageDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	src := fmt.Sprintf("package %s\n\nfunc (a *%s) Double() int {\n\treturn a.Count * 2\n}\n", pkg.Name, conf.Type)
+	return c.Outputs.Write(c.Parser, c.Path(conf.Output), []byte(src))
+}
+
+func TestRun(t *testing.T) {
+	Run(t, "testdata", generateDouble)
+}
+
+func TestTypeCheck(t *testing.T) {
+	output := filepath.Join("testdata", "double", "zz_double.go")
+	err := TypeCheck(map[string][]byte{
+		output: []byte("package model\n\nfunc (a *A) Double() string {\n\treturn a.Count * 2\n}\n"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "cannot use a.Count * 2") {
+		t.Errorf("expect the type error, got %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+package model
+
+type A struct {
+	Count int
+}
@@ -0,0 +1,195 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// VerifyError is returned when the generated output does not type check
+type VerifyError struct {
+	Output string
+	// Errors are the type errors in the output, with the generated lines
+	Errors []string
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s does not compile:\n%s", e.Output, strings.Join(e.Errors, "\n"))
+}
+
+// VerifyOutput type checks the package of output with the result in place of the file, nothing is written,
+// the files are chosen by the ImportContext of the parser, and its Overlay is applied
+// only the errors in the result are reported, the other files may wait for the outputs not generated yet
+func VerifyOutput(p *parser.Parser, output string, result []byte) error {
+	if len(output) == 0 {
+		return errors.New("verify mode requires an output file")
+	}
+	abs, err := filepath.Abs(output)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", output, err)
+	}
+
+	overlay := p.CopyOverlay()
+	if overlay == nil {
+		overlay = map[string][]byte{}
+	}
+	overlay[abs] = result
+	typeErrors, err := TypeCheck(&p.ImportContext, filepath.Dir(abs), overlay)
+	if err != nil {
+		return fmt.Errorf("verify %s: %w", output, err)
+	}
+
+	lines := splitLines(string(result))
+	e := &VerifyError{Output: output}
+	for _, typeErr := range typeErrors {
+		pos := typeErr.Fset.Position(typeErr.Pos)
+		if pos.Filename != abs {
+			continue
+		}
+		pos.Filename = output
+		msg := fmt.Sprintf("%s: %s", pos, typeErr.Msg)
+		if pos.Line > 0 && pos.Line <= len(lines) {
+			msg += "\n\t" + strings.TrimSpace(lines[pos.Line-1])
+		}
+		e.Errors = append(e.Errors, msg)
+	}
+	if len(e.Errors) > 0 {
+		return e
+	}
+	return nil
+}
+
+// TypeCheck type checks the package in dir, the files are chosen by ctx,
+// the overlay replaces the files by absolute path, or adds the ones in dir to the package
+// the imports are found by ctx and type checked from source with the overlay too, the type errors are returned
+func TypeCheck(ctx *build.Context, dir string, overlay map[string][]byte) ([]types.Error, error) {
+	buildPkg, err := ctx.ImportDir(dir, 0)
+	var noGo *build.NoGoError
+	if err != nil && !errors.As(err, &noGo) {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files, err := parseFiles(fset, dir, buildPkg, overlay)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%s: no buildable Go files", dir)
+	}
+
+	sizes := types.SizesFor("gc", ctx.GOARCH)
+	typeErrors := []types.Error{}
+	config := &types.Config{
+		Importer: &sourceImporter{
+			ctx:      ctx,
+			fset:     fset,
+			overlay:  overlay,
+			sizes:    sizes,
+			dir:      dir,
+			packages: map[string]*types.Package{},
+		},
+		Sizes:       sizes,
+		FakeImportC: true,
+		Error: func(err error) {
+			if typeErr, ok := err.(types.Error); ok {
+				typeErrors = append(typeErrors, typeErr)
+			}
+		},
+	}
+	config.Check(files[0].Name.Name, fset, files, nil)
+	return typeErrors, nil
+}
+
+// parseFiles parses the go and cgo files of the package in dir, with the overlay applied,
+// buildPkg is nil if there are only the files of the overlay
+func parseFiles(fset *token.FileSet, dir string, buildPkg *build.Package, overlay map[string][]byte) ([]*ast.File, error) {
+	names := []string{}
+	if buildPkg != nil {
+		for _, name := range append(append([]string{}, buildPkg.GoFiles...), buildPkg.CgoFiles...) {
+			if name := filepath.Join(dir, name); overlay[name] == nil {
+				names = append(names, name)
+			}
+		}
+	}
+	added := []string{}
+	for name := range overlay {
+		if filepath.Dir(name) == dir {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+	names = append(names, added...)
+
+	files := []*ast.File{}
+	for _, name := range names {
+		var src interface{}
+		if content, ok := overlay[name]; ok {
+			src = content
+		}
+		file, err := goparser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// sourceImporter type checks the imported packages from source,
+// they are found by the build context, so the files are chosen by its tags, GOOS and GOARCH,
+// and the overlay replaces their files too, the type errors in them are ignored
+type sourceImporter struct {
+	ctx     *build.Context
+	fset    *token.FileSet
+	overlay map[string][]byte
+	sizes   types.Sizes
+	// dir of the package type checked
+	dir string
+	// packages by import path, nil while it is type checked
+	packages map[string]*types.Package
+}
+
+func (imp *sourceImporter) Import(path string) (*types.Package, error) {
+	return imp.ImportFrom(path, imp.dir, 0)
+}
+
+func (imp *sourceImporter) ImportFrom(path string, dir string, mode types.ImportMode) (*types.Package, error) {
+	if path == "unsafe" {
+		return types.Unsafe, nil
+	}
+	buildPkg, err := imp.ctx.Import(path, dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pkg, ok := imp.packages[buildPkg.ImportPath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through package %s", buildPkg.ImportPath)
+		}
+		return pkg, nil
+	}
+
+	imp.packages[buildPkg.ImportPath] = nil
+	files, err := parseFiles(imp.fset, buildPkg.Dir, buildPkg, imp.overlay)
+	if err != nil {
+		return nil, err
+	}
+	config := &types.Config{
+		Importer:         imp,
+		Sizes:            imp.sizes,
+		IgnoreFuncBodies: true,
+		FakeImportC:      true,
+		Error:            func(error) {},
+	}
+	pkg, _ := config.Check(buildPkg.ImportPath, imp.fset, files, nil)
+	imp.packages[buildPkg.ImportPath] = pkg
+	return pkg, nil
+}
@@ -0,0 +1,145 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+func TestVerifyOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.go": "package a\n\ntype A struct {\n\tName string\n}\n",
+		// the old output is replaced, its error is not reported
+		"zz_generated.go": "package a\n\nfunc (a *A) Old() int { return a.Missing }\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := parser.NewParser()
+	output := filepath.Join(dir, "zz_generated.go")
+	good := "package a\n\nimport \"strings\"\n\nfunc (a *A) Upper() string {\n\treturn strings.ToUpper(a.Name)\n}\n"
+	if err := VerifyOutput(p, output, []byte(good)); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+
+	bad := "package a\n\nfunc (a *A) Count() int {\n\treturn a.Name\n}\n"
+	err = VerifyOutput(p, output, []byte(bad))
+	var verifyErr *VerifyError
+	if !errors.As(err, &verifyErr) {
+		t.Fatalf("expect a VerifyError, got %v", err)
+	}
+	if len(verifyErr.Errors) != 1 {
+		t.Fatalf("expect 1 error, got %v", verifyErr.Errors)
+	}
+	msg := verifyErr.Errors[0]
+	if !strings.HasPrefix(msg, output+":4:9: cannot use a.Name") || !strings.HasSuffix(msg, "\n\treturn a.Name") {
+		t.Errorf("unexpected error: %s", msg)
+	}
+
+	// the outputs kept in memory are seen by the next ones
+	other := filepath.Join(dir, "zz_other.go")
+	if err := p.AddOverlay(output, []byte(good)); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyOutput(p, other, []byte("package a\n\nvar _ = (&A{}).Upper()\n")); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+}
+
+// the generators running at once verify their outputs while the others are added, see go test -race
+func TestVerifyOutputConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\ntype A int\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := parser.NewParser()
+	outputs := &Outputs{}
+	started := make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		for i := 0; ; i++ {
+			output := filepath.Join(dir, fmt.Sprintf("zz_%d.go", i%10))
+			if err := outputs.Write(p, output, []byte(fmt.Sprintf("package a\n\nvar X%d A\n", i%10))); err != nil {
+				done <- err
+				return
+			}
+			if i == 0 {
+				close(started)
+			}
+			select {
+			case <-stop:
+				done <- nil
+				return
+			default:
+			}
+		}
+	}()
+	<-started
+	for i := 0; i < 100; i++ {
+		if err := VerifyOutput(p, filepath.Join(dir, "zz_verify.go"), []byte("package a\n\nvar Y A\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// the imported packages are chosen by the tags of the parser, and their overlaid files are seen
+func TestVerifyOutputImports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"go.mod":         "module example.com/v\n\ngo 1.18\n",
+		"a/a.go":         "package a\n",
+		"b/b_foo.go":     "//go:build foo\n\npackage b\n\nconst X = 1\n",
+		"b/b_default.go": "//go:build !foo\n\npackage b\n\nconst X = \"x\"\n",
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := parser.NewParser()
+	p.ImportContext.Dir = dir
+	p.ImportContext.BuildTags = []string{"foo"}
+	if err := p.AddOverlay(filepath.Join(dir, "b", "zz_new.go"), []byte("package b\n\nfunc New() int { return X }\n")); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "a", "zz_generated.go")
+	result := "package a\n\nimport \"example.com/v/b\"\n\nvar Y int = b.X + b.New()\n"
+	if err := VerifyOutput(p, output, []byte(result)); err != nil {
+		t.Errorf("expect no error, got %v", err)
+	}
+}
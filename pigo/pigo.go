@@ -0,0 +1,164 @@
+// Package pigo runs the generators in process, the generated files are returned instead of written, e.g.
+//
+//	files, err := pigo.Run(ctx, pigo.CheckerTask{Dir: "model", Type: "CreateParam", Output: "zz_checker.go"})
+//
+// the tasks are the same as the tasks of pigo.yaml, see pigo help run
+package pigo
+
+import (
+	"context"
+
+	"github.com/lawrsp/pigo/cmd/checker"
+	"github.com/lawrsp/pigo/cmd/convert"
+	"github.com/lawrsp/pigo/cmd/evalid"
+	"github.com/lawrsp/pigo/cmd/genrpc"
+	"github.com/lawrsp/pigo/cmd/jsonfield"
+	"github.com/lawrsp/pigo/cmd/pfilter"
+	"github.com/lawrsp/pigo/cmd/run"
+	"github.com/lawrsp/pigo/cmd/setdb"
+	"github.com/lawrsp/pigo/cmd/setter"
+	"github.com/lawrsp/pigo/cmd/template"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// Task is one generation, the package is in Dir,
+// and the other paths of the task are relative to Dir
+type Task interface {
+	manifestTask() *run.Task
+}
+
+// CheckerTask generates the validate function, see pigo help checker
+type CheckerTask checker.Config
+
+// ConvertTask generates the convert functions, see pigo help convert
+type ConvertTask convert.YamlConfig
+
+// EvalidTask generates the enum validate function, see pigo help evalid
+type EvalidTask evalid.Config
+
+// GenrpcTask generates the rpc glue code, see pigo help genrpc
+type GenrpcTask genrpc.YamlConfig
+
+// JsonfieldTask generates the json field map, see pigo help jsonfield
+type JsonfieldTask jsonfield.Config
+
+// PfilterTask generates the field mask filter, see pigo help pfilter
+type PfilterTask pfilter.Config
+
+// SetdbTask generates the db where clauses, see pigo help setdb
+type SetdbTask setdb.Config
+
+// SetterTask generates the set function, see pigo help setter
+type SetterTask setter.Config
+
+// TemplateTask generates the code of a text/template, see pigo help template
+type TemplateTask template.Config
+
+// PluginTask runs the plugin Name, the Args are set to its config, see plugin.SetConfig
+type PluginTask struct {
+	Dir  string
+	Name string
+	Args map[string]interface{}
+}
+
+func (t CheckerTask) manifestTask() *run.Task {
+	c := checker.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Checker: &c}
+}
+
+func (t ConvertTask) manifestTask() *run.Task {
+	c := convert.YamlConfig(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Convert: &c}
+}
+
+func (t EvalidTask) manifestTask() *run.Task {
+	c := evalid.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Evalid: &c}
+}
+
+func (t GenrpcTask) manifestTask() *run.Task {
+	c := genrpc.YamlConfig(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Genrpc: &c}
+}
+
+func (t JsonfieldTask) manifestTask() *run.Task {
+	c := jsonfield.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Jsonfield: &c}
+}
+
+func (t PfilterTask) manifestTask() *run.Task {
+	c := pfilter.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Pfilter: &c}
+}
+
+func (t SetdbTask) manifestTask() *run.Task {
+	c := setdb.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Setdb: &c}
+}
+
+func (t SetterTask) manifestTask() *run.Task {
+	c := setter.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Setter: &c}
+}
+
+func (t TemplateTask) manifestTask() *run.Task {
+	c := template.Config(t)
+	c.Dir = ""
+	return &run.Task{Dir: t.Dir, Template: &c}
+}
+
+func (t PluginTask) manifestTask() *run.Task {
+	return &run.Task{Dir: t.Dir, Plugins: map[string]map[string]interface{}{t.Name: t.Args}}
+}
+
+// Runner runs the tasks with one parser, nothing is written
+type Runner struct {
+	// Parser parses the packages, the generated files are overlaid on it, see parser.Parser.Overlay
+	Parser *parser.Parser
+	// Header is written on top of the outputs, see generator.CodeHeader
+	Header string
+	// Verify type checks the outputs, see generator.VerifyOutput
+	Verify bool
+	// Jobs is the number of tasks run at once, runtime.GOMAXPROCS if 0, see run.Runner
+	Jobs int
+}
+
+func NewRunner() *Runner {
+	return &Runner{Parser: parser.NewParser()}
+}
+
+// Run runs the tasks as in order, and returns the generated files by output, joined with the Dir of the task
+// the tasks can share an output as in pigo.yaml, the existing outputs are read but never written
+func (r *Runner) Run(ctx context.Context, tasks ...Task) (map[string][]byte, error) {
+	m := &run.Manifest{}
+	for _, t := range tasks {
+		m.Tasks = append(m.Tasks, t.manifestTask())
+	}
+
+	runner := run.NewRunner()
+	if r.Parser != nil {
+		runner.Parser = r.Parser
+	}
+	runner.Header = r.Header
+	runner.Verify = r.Verify
+	runner.Jobs = r.Jobs
+	runner.Outputs = &generator.Outputs{}
+	if err := runner.RunContext(ctx, m, "."); err != nil {
+		return nil, err
+	}
+	return runner.Outputs.Files(), nil
+}
+
+// Run runs the tasks with a new Runner
+func Run(ctx context.Context, tasks ...Task) (map[string][]byte, error) {
+	return NewRunner().Run(ctx, tasks...)
+}
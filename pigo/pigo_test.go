@@ -0,0 +1,223 @@
+package pigo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/cmd/genrpc"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/plugin"
+)
+
+const modelSrc = `package model
+
+type A struct {
+	Name string
+}
+
+type B struct {
+	Name string
+}
+`
+
+func writeModel(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte(modelSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+
+	files, err := Run(context.Background(),
+		SetterTask{Dir: dir, Type: "A", Target: "B", Output: "zz_setter.go"},
+		SetterTask{Dir: dir, Type: "B", Target: "A", Output: "zz_setter.go"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "zz_setter.go")
+	if len(files) != 1 || files[output] == nil {
+		t.Fatalf("expect only %s, got %d files", output, len(files))
+	}
+	src := string(files[output])
+	for _, expect := range []string{"//pigo:owner setter A SetB\n", "//pigo:owner setter B SetA\n", "func (t *A) SetB(", "func (t *B) SetA("} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expect %q in:\n%s", expect, src)
+		}
+	}
+
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("%s should not be written: %v", output, err)
+	}
+}
+
+const rpcSrc = `package model
+
+type Server interface {
+	Get(id int) (int, error)
+}
+
+type S struct{}
+
+func get(id int) (int, error) {
+	return id, nil
+}
+`
+
+// the generated code is returned, nothing is printed to stdout
+func TestRunQuiet(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "rpc.go"), []byte(rpcSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	printed := make(chan []byte)
+	go func() {
+		out, _ := ioutil.ReadAll(r)
+		printed <- out
+	}()
+
+	files, err := Run(context.Background(),
+		CheckerTask{Dir: dir, Type: "A", Output: "zz_checker.go"},
+		GenrpcTask{
+			Dir:       dir,
+			Receiver:  "S",
+			Interface: "Server",
+			Output:    "zz_rpc.go",
+			Generates: map[string]genrpc.TaskDesc{"Get": {Sequence: []genrpc.ProcDesc{{Call: "get"}}}},
+		},
+	)
+	os.Stdout = stdout
+	w.Close()
+	out := <-printed
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Errorf("expect 2 files, got %d", len(files))
+	}
+	if len(out) > 0 {
+		t.Errorf("expect nothing printed, got:\n%s", out)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Run(ctx, SetterTask{Dir: dir, Type: "A", Target: "B", Output: "zz_setter.go"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expect %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pigo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/p\n\ngo 1.18\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tasks := []Task{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		pkg := filepath.Join(dir, name)
+		if err := os.Mkdir(pkg, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(pkg, "model.go"), []byte(modelSrc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks,
+			SetterTask{Dir: pkg, Type: "A", Target: "B", Output: "zz_setter.go"},
+			JsonfieldTask{Dir: pkg, Type: "A", Output: "zz_json.go"},
+			SetterTask{Dir: pkg, Type: "B", Target: "A", Output: "zz_setter.go"},
+		)
+	}
+
+	expect, err := (&Runner{Jobs: 1}).Run(context.Background(), tasks...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		files, err := (&Runner{Jobs: 8}).Run(context.Background(), tasks...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != len(expect) {
+			t.Fatalf("expect %d files, got %d", len(expect), len(files))
+		}
+		for output, src := range expect {
+			if string(files[output]) != string(src) {
+				t.Errorf("%s: expect\n%s\ngot\n%s", output, src, files[output])
+			}
+		}
+	}
+}
+
+type namerConfig struct {
+	plugin.Config
+	Name string
+}
+
+// namer generates a func returns the name of the type
+type namer struct{}
+
+func (namer) Name() string           { return "namer" }
+func (namer) Usage() string          { return "generate the type name func" }
+func (namer) NewConfig() interface{} { return &namerConfig{} }
+
+func (namer) Generate(g *generator.Generator, config interface{}) error {
+	c := config.(*namerConfig)
+	return plugin.AddCode(g, fmt.Sprintf("func (%s) %s() string {\n\treturn %q\n}\n", c.Type, c.Name, c.Type), nil)
+}
+
+func TestRunPlugin(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+	plugin.Register(namer{})
+
+	files, err := Run(context.Background(),
+		SetterTask{Dir: dir, Type: "A", Target: "B", Output: "zz_generated.go"},
+		PluginTask{Dir: dir, Name: "namer", Args: map[string]interface{}{"type": "A", "name": "TypeName", "output": "zz_generated.go"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(files[filepath.Join(dir, "zz_generated.go")])
+	for _, expect := range []string{"//pigo:owner setter A SetB\n", "//pigo:owner namer A TypeName\n", "func (A) TypeName() string {"} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expect %q in:\n%s", expect, src)
+		}
+	}
+
+	_, err = Run(context.Background(), PluginTask{Dir: dir, Name: "namer", Args: map[string]interface{}{"type": "A", "other": "x", "output": "zz_generated.go"}})
+	if err == nil || !strings.Contains(err.Error(), "unknown argument other") {
+		t.Errorf("expect the unknown argument, got %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package plugin
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/lawrsp/pigo/cmd/cmdutil"
+	"github.com/lawrsp/pigo/generator"
+	"github.com/urfave/cli"
+)
+
+// the fields of a config are named by the pigo tag, or the lower case field name,
+// `pigo:"-"` skips the field, and `pigo:",rest"` on a map[string]interface{} keeps the unknown values
+// the fields can be string, bool, int, []string and map[string]string, the embedded structs are spread
+type configField struct {
+	name  string
+	usage string
+	rest  bool
+	value reflect.Value
+}
+
+func configFields(config interface{}) ([]*configField, error) {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config %T should be a pointer to struct", config)
+	}
+	fields := []*configField{}
+	if err := appendFields(&fields, v.Elem()); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func appendFields(fields *[]*configField, v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		tag := strings.Split(sf.Tag.Get("pigo"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			if err := appendFields(fields, v.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		f := &configField{
+			name:  tag[0],
+			usage: sf.Tag.Get("usage"),
+			rest:  len(tag) > 1 && tag[1] == "rest",
+			value: v.Field(i),
+		}
+		if f.name == "" {
+			f.name = strings.ToLower(sf.Name)
+		}
+		if f.rest {
+			if sf.Type != reflect.TypeOf(map[string]interface{}{}) {
+				return fmt.Errorf("rest field %s should be map[string]interface{}", sf.Name)
+			}
+		} else if !supported(sf.Type) {
+			return fmt.Errorf("field %s: type %s is not supported", sf.Name, sf.Type)
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+// configName returns the "name" field of the config, which names the generated declarations, or ""
+func configName(config interface{}) string {
+	fields, err := configFields(config)
+	if err != nil {
+		return ""
+	}
+	for _, f := range fields {
+		if f.name == "name" && f.value.Kind() == reflect.String {
+			return f.value.String()
+		}
+	}
+	return ""
+}
+
+func supported(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	case reflect.Map:
+		return t.Key().Kind() == reflect.String && t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// SetConfig sets the fields of config by name,
+// the values are from pigo.yaml, the flags or the directive arguments, so a string is converted to the field type:
+// "true" for bool, "a,b" for []string and "name:path,path2" for map[string]string as the imports
+func SetConfig(config interface{}, values map[string]interface{}) error {
+	fields, err := configFields(config)
+	if err != nil {
+		return err
+	}
+	byName := map[string]*configField{}
+	var rest *configField
+	for _, f := range fields {
+		if f.rest {
+			rest = f
+			continue
+		}
+		byName[f.name] = f
+	}
+
+	unknown := []string{}
+	for _, key := range generator.SortedKeys(values) {
+		f, ok := byName[key]
+		if !ok {
+			if rest == nil {
+				unknown = append(unknown, key)
+				continue
+			}
+			if rest.value.IsNil() {
+				rest.value.Set(reflect.ValueOf(map[string]interface{}{}))
+			}
+			rest.value.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(values[key]))
+			continue
+		}
+		if err := setValue(f.value, values[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown argument %s", strings.Join(unknown, ","))
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, value interface{}) error {
+	switch v.Kind() {
+	case reflect.String:
+		switch x := value.(type) {
+		case string:
+			v.SetString(x)
+		case int, bool, float64:
+			v.SetString(fmt.Sprint(x))
+		default:
+			return fmt.Errorf("%v is not a string", value)
+		}
+	case reflect.Bool:
+		switch x := value.(type) {
+		case bool:
+			v.SetBool(x)
+		case string:
+			b, err := strconv.ParseBool(x)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		default:
+			return fmt.Errorf("%v is not a bool", value)
+		}
+	case reflect.Int:
+		switch x := value.(type) {
+		case int:
+			v.SetInt(int64(x))
+		case string:
+			n, err := strconv.Atoi(x)
+			if err != nil {
+				return err
+			}
+			v.SetInt(int64(n))
+		default:
+			return fmt.Errorf("%v is not an int", value)
+		}
+	case reflect.Slice:
+		list, err := stringList(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		m := map[string]string{}
+		switch x := value.(type) {
+		case map[string]string:
+			m = x
+		case map[string]interface{}:
+			for key, item := range x {
+				m[key] = fmt.Sprint(item)
+			}
+		case map[interface{}]interface{}:
+			for key, item := range x {
+				m[fmt.Sprint(key)] = fmt.Sprint(item)
+			}
+		default:
+			list, err := stringList(value)
+			if err != nil {
+				return err
+			}
+			m = cmdutil.Imports(list)
+		}
+		v.Set(reflect.ValueOf(m))
+	}
+	return nil
+}
+
+func stringList(value interface{}) ([]string, error) {
+	switch x := value.(type) {
+	case []string:
+		return x, nil
+	case string:
+		if x == "" {
+			return nil, nil
+		}
+		return strings.Split(x, ","), nil
+	case []interface{}:
+		list := []string{}
+		for _, item := range x {
+			list = append(list, fmt.Sprint(item))
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%v is not a list", value)
+}
+
+// Flags returns the command flags of the config fields
+func Flags(config interface{}) []cli.Flag {
+	fields, err := configFields(config)
+	if err != nil {
+		panic(err)
+	}
+	flags := []cli.Flag{}
+	for _, f := range fields {
+		switch {
+		case f.rest:
+		case f.value.Kind() == reflect.Bool:
+			flags = append(flags, cli.BoolFlag{Name: f.name, Usage: f.usage})
+		case f.value.Kind() == reflect.Int:
+			flags = append(flags, cli.IntFlag{Name: f.name, Usage: f.usage})
+		case f.value.Kind() == reflect.Slice, f.value.Kind() == reflect.Map:
+			flags = append(flags, cli.StringSliceFlag{Name: f.name, Usage: f.usage})
+		default:
+			flags = append(flags, cli.StringFlag{Name: f.name, Usage: f.usage})
+		}
+	}
+	return flags
+}
+
+// flagValues returns the values of the flags set in the command line
+func flagValues(c *cli.Context, config interface{}) (map[string]interface{}, error) {
+	fields, err := configFields(config)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	for _, f := range fields {
+		if f.rest || !c.IsSet(f.name) {
+			continue
+		}
+		switch f.value.Kind() {
+		case reflect.Bool:
+			values[f.name] = c.Bool(f.name)
+		case reflect.Int:
+			values[f.name] = c.Int(f.name)
+		case reflect.Slice, reflect.Map:
+			values[f.name] = c.StringSlice(f.name)
+		default:
+			values[f.name] = c.String(f.name)
+		}
+	}
+	return values, nil
+}
+
+// Command returns the command of the plugin, the flags are the config fields
+func Command(p Plugin) cli.Command {
+	return cli.Command{
+		Name:      p.Name(),
+		UsageText: fmt.Sprintf("pigo %s [command options]", p.Name()),
+		Usage:     p.Usage(),
+		Flags:     Flags(p.NewConfig()),
+		Action: func(c *cli.Context) error {
+			config := p.NewConfig()
+			values, err := flagValues(c, config)
+			if err != nil {
+				return err
+			}
+			if err := SetConfig(config, values); err != nil {
+				return err
+			}
+
+			g := &generator.Generator{}
+			g.Header = cmdutil.Header(c)
+			g.Check = c.GlobalBool("check")
+			g.Verify = c.GlobalBool("verify")
+			g.Parser = cmdutil.Parser(c)
+			return Run(p, g, config)
+		},
+	}
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
+	"github.com/lawrsp/pigo/generator/parser"
+)
+
+// ExecPrefix is the prefix of the plugin commands in PATH, e.g. pigo-mygen for the generator mygen
+const ExecPrefix = "pigo-"
+
+// RequestVersion is the version of the Request, changed when it is incompatible
+const RequestVersion = "1"
+
+// ExecPlugin runs a command to generate, the Request is written to its stdin as JSON,
+// and the Response is read from its stdout, the stderr is passed through
+type ExecPlugin struct {
+	name string
+	// Command is the program and its arguments
+	Command []string
+}
+
+func NewExecPlugin(name string, command ...string) *ExecPlugin {
+	return &ExecPlugin{name: name, Command: command}
+}
+
+func (p *ExecPlugin) Name() string {
+	return p.name
+}
+
+func (p *ExecPlugin) Usage() string {
+	return "run " + p.Command[0]
+}
+
+// ExecConfig is the config of an ExecPlugin, the other arguments are sent in Request.Args
+type ExecConfig struct {
+	Config
+	Args map[string]interface{} `pigo:",rest"`
+}
+
+func (p *ExecPlugin) NewConfig() interface{} {
+	return &ExecConfig{}
+}
+
+// Request is sent to the command of an ExecPlugin
+type Request struct {
+	Version   string                 `json:"version"`
+	Generator string                 `json:"generator"`
+	Output    string                 `json:"output"`
+	Args      map[string]interface{} `json:"args,omitempty"`
+	Package   *PackageModel          `json:"package"`
+	// Type is the Type of the config, nil if not given
+	Type *TypeModel `json:"type,omitempty"`
+}
+
+// PackageModel is the package of the output
+type PackageModel struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	Dir  string `json:"dir"`
+}
+
+// TypeModel is a type, the type expressions are as written in the output file
+type TypeModel struct {
+	Name string `json:"name"`
+	// Underlying is "struct" for the structs, or the expression of the underlying type
+	Underlying string        `json:"underlying"`
+	Fields     []*FieldModel `json:"fields,omitempty"`
+}
+
+// FieldModel is a struct field, the Name of an embedded field is its type name
+type FieldModel struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Tag      string `json:"tag,omitempty"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+// Response is read from the command of an ExecPlugin
+type Response struct {
+	// Code is the declarations to add to the output, without the package clause, see AddCode
+	Code string `json:"code"`
+	// Imports are the imports of the code by name, as the --import of the generators
+	Imports map[string]string `json:"imports,omitempty"`
+	// Error fails the generation if not empty
+	Error string `json:"error,omitempty"`
+}
+
+func (p *ExecPlugin) Generate(g *generator.Generator, config interface{}) error {
+	c, ok := config.(*ExecConfig)
+	if !ok {
+		return fmt.Errorf("config %T is not *plugin.ExecConfig", config)
+	}
+	req := &Request{
+		Version:   RequestVersion,
+		Generator: p.name,
+		Output:    c.Output,
+		Args:      c.Args,
+		Package: &PackageModel{
+			Name: g.Pkg.Name,
+			Path: g.Pkg.Path,
+			Dir:  g.Pkg.Dir,
+		},
+	}
+	if c.Type != "" {
+		req.Type = NewTypeModel(g, c.Type)
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run %s: %w", p.Command[0], err)
+	}
+
+	resp := &Response{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return fmt.Errorf("read response of %s: %w", p.Command[0], err)
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return AddCode(g, resp.Code, resp.Imports)
+}
+
+// NewTypeModel returns the model of the type expression src in the output file
+func NewTypeModel(g *generator.Generator, src string) *TypeModel {
+	t := g.ReduceTypeSrc(src)
+	if t == nil {
+		errutil.Throw(errutil.New("type not reduced").WithExpr(src))
+	}
+
+	m := &TypeModel{Name: src}
+	st, ok := t.Underlying().(*parser.StructType)
+	if !ok {
+		m.Underlying = parser.ExprToString(parser.TypeExprInFile(t.Underlying(), g.File))
+		return m
+	}
+	m.Underlying = "struct"
+	for _, field := range st.Fields {
+		parser.ResolveUnknownField(field)
+		m.Fields = append(m.Fields, &FieldModel{
+			Name:     field.Name(),
+			Type:     parser.ExprToString(parser.TypeExprInFile(field.Type, g.File)),
+			Tag:      field.Tag,
+			Embedded: field.IsAnonymous(),
+		})
+	}
+	return m
+}
+
+// AddCode adds the declarations in code and the imports by name to the output file, see generator.Generator.AddCode
+func AddCode(g *generator.Generator, code string, imports map[string]string) error {
+	if err := g.PrepareImports(imports); err != nil {
+		return err
+	}
+	return g.AddCode(code)
+}
@@ -0,0 +1,142 @@
+// Package plugin adds generators to pigo, a plugin registered in init is compiled into a custom binary by one import:
+//
+//	package main
+//
+//	import (
+//		"github.com/lawrsp/pigo/cmd/app"
+//
+//		_ "example.com/project/tools/mygen"
+//	)
+//
+//	func main() {
+//		app.Main("1.0.0")
+//	}
+//
+// the plugin is a command of the binary, a generator of pigo.yaml and of the //pigo: directives,
+// the generators not compiled in are run as pigo-<name> in PATH, see ExecPlugin
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/errutil"
+)
+
+// Plugin is a generator, the declarations are generated into the output of the config
+type Plugin interface {
+	// Name is the command, and the generator in pigo.yaml and the directives
+	Name() string
+	// Usage is the one line help of the command
+	Usage() string
+	// NewConfig returns a pointer to the config struct, which embeds Config,
+	// its fields are the flags and the arguments of the generator, see SetConfig
+	NewConfig() interface{}
+	// Generate adds the declarations of the config to g.File, see builder.NewFile and AddCode,
+	// g is prepared with the package of the Dir and the existing output
+	Generate(g *generator.Generator, config interface{}) error
+}
+
+// Config is the common config of the plugins
+type Config struct {
+	Dir    string `pigo:"-"`
+	Type   string `usage:"the TYPE to generate"`
+	Output string `usage:"the FILE to output"`
+}
+
+func (c *Config) common() *Config {
+	return c
+}
+
+type commonConfig interface {
+	common() *Config
+}
+
+// Common returns the Config embedded in config
+func Common(config interface{}) (*Config, error) {
+	c, ok := config.(commonConfig)
+	if !ok {
+		return nil, fmt.Errorf("config %T does not embed plugin.Config", config)
+	}
+	return c.common(), nil
+}
+
+var (
+	plugins = map[string]Plugin{}
+	ordered = []Plugin{}
+)
+
+// Register adds the plugin, it panics if the name is registered
+func Register(p Plugin) {
+	name := p.Name()
+	if name == "" || strings.ContainsAny(name, " :=") {
+		panic(fmt.Sprintf("plugin: invalid name %q", name))
+	}
+	if _, ok := plugins[name]; ok {
+		panic(fmt.Sprintf("plugin: %s is registered twice", name))
+	}
+	if _, err := Common(p.NewConfig()); err != nil {
+		panic(fmt.Sprintf("plugin: %s: %v", name, err))
+	}
+	plugins[name] = p
+	ordered = append(ordered, p)
+}
+
+// Lookup returns the registered plugin, or nil
+func Lookup(name string) Plugin {
+	return plugins[name]
+}
+
+// Plugins returns the registered plugins in the order they are registered
+func Plugins() []Plugin {
+	return append([]Plugin{}, ordered...)
+}
+
+// Find returns the registered plugin, or the ExecPlugin of pigo-<name> in PATH
+func Find(name string) (Plugin, error) {
+	if p := Lookup(name); p != nil {
+		return p, nil
+	}
+	path, err := exec.LookPath(ExecPrefix + name)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("unknown generator %s", name)
+		}
+		return nil, err
+	}
+	return NewExecPlugin(name, path), nil
+}
+
+// Run generates the config into its output
+func Run(p Plugin, g *generator.Generator, config interface{}) error {
+	return RunAll(p, g, []interface{}{config})
+}
+
+// RunAll generates the configs into one output,
+// the Dir and Output of the first config are used
+func RunAll(p Plugin, g *generator.Generator, configs []interface{}) (err error) {
+	defer func() { err = errutil.WithGenerator(err, p.Name()) }()
+	defer errutil.Catch(&err)
+
+	first, err := Common(configs[0])
+	if err != nil {
+		return err
+	}
+	if err := g.Prepare(first.Dir, nil, first.Output); err != nil {
+		return err
+	}
+	for _, config := range configs {
+		c, err := Common(config)
+		if err != nil {
+			return err
+		}
+		g.Own(strings.Join(strings.Fields(p.Name()+" "+c.Type+" "+configName(config)), " "))
+		if err := p.Generate(g, config); err != nil {
+			return err
+		}
+	}
+	return g.Output(first.Output)
+}
@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lawrsp/pigo/generator"
+	"github.com/lawrsp/pigo/generator/testutil"
+)
+
+type stringerConfig struct {
+	Config
+	Name    string `usage:"the FUNCTION to generate"`
+	Quote   bool   `pigo:"quote"`
+	Skip    []string
+	Imports map[string]string `pigo:"import"`
+	Width   int
+}
+
+// stringer generates a String function which returns the field names
+type stringer struct{}
+
+func (stringer) Name() string           { return "stringer" }
+func (stringer) Usage() string          { return "generate String" }
+func (stringer) NewConfig() interface{} { return &stringerConfig{} }
+
+func (stringer) Generate(g *generator.Generator, config interface{}) error {
+	c := config.(*stringerConfig)
+	m := NewTypeModel(g, c.Type)
+	names := []string{}
+	for _, f := range m.Fields {
+		names = append(names, f.Name)
+	}
+	code := fmt.Sprintf("func (t *%s) %s() string {\n\treturn strings.Join([]string{%q}, \",\")\n}\n",
+		c.Type, c.Name, strings.Join(names, ","))
+	return AddCode(g, code, map[string]string{"strings": "strings"})
+}
+
+const modelSrc = `package model
+
+type Base struct {
+	ID int
+}
+
+type User struct {
+	Base
+	Name  string ` + "`json:\"name\"`" + `
+	Tags  []string
+	Owner *Base
+}
+`
+
+func writeModel(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "plugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "model.go"), []byte(modelSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSetConfig(t *testing.T) {
+	c := &stringerConfig{}
+	err := SetConfig(c, map[string]interface{}{
+		"type":   "User",
+		"name":   "Names",
+		"quote":  "true",
+		"skip":   "a,b",
+		"import": []interface{}{"m:example.com/model", "example.com/x"},
+		"width":  3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &stringerConfig{
+		Config:  Config{Type: "User"},
+		Name:    "Names",
+		Quote:   true,
+		Skip:    []string{"a", "b"},
+		Imports: map[string]string{"m": "example.com/model", "x": "example.com/x"},
+		Width:   3,
+	}
+	if !reflect.DeepEqual(c, expect) {
+		t.Errorf("expect %+v, got %+v", expect, c)
+	}
+
+	if err := SetConfig(&stringerConfig{}, map[string]interface{}{"dir": "x", "other": "y"}); err == nil ||
+		err.Error() != "unknown argument dir,other" {
+		t.Errorf("expect the unknown arguments, got %v", err)
+	}
+	if err := SetConfig(&stringerConfig{}, map[string]interface{}{"width": "wide"}); err == nil {
+		t.Error("expect an error of width")
+	}
+
+	ec := &ExecConfig{}
+	if err := SetConfig(ec, map[string]interface{}{"type": "User", "other": "y"}); err != nil {
+		t.Fatal(err)
+	}
+	if ec.Type != "User" || !reflect.DeepEqual(ec.Args, map[string]interface{}{"other": "y"}) {
+		t.Errorf("expect the rest in Args, got %+v", ec)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	names := []string{}
+	for _, flag := range Flags(&stringerConfig{}) {
+		names = append(names, flag.GetName())
+	}
+	expect := []string{"type", "output", "name", "quote", "skip", "import", "width"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("expect %v, got %v", expect, names)
+	}
+	if len(Flags(&ExecConfig{})) != 2 {
+		t.Error("expect no flags of the rest")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(stringer{})
+	defer func() {
+		delete(plugins, "stringer")
+		ordered = ordered[:len(ordered)-1]
+	}()
+
+	if p, err := Find("stringer"); err != nil || p.Name() != "stringer" {
+		t.Errorf("expect stringer, got %v, %v", p, err)
+	}
+	if _, err := Find("nothing-like-this"); err == nil {
+		t.Error("expect an unknown generator")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expect a panic of registering twice")
+		}
+	}()
+	Register(stringer{})
+}
+
+func TestRun(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "zz_generated.go")
+	g := &generator.Generator{Outputs: &generator.Outputs{}}
+	err := RunAll(stringer{}, g, []interface{}{
+		&stringerConfig{Config: Config{Dir: dir, Type: "User", Output: output}, Name: "Names"},
+		&stringerConfig{Config: Config{Dir: dir, Type: "Base", Output: output}, Name: "Names"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(g.Outputs.Get(output))
+	for _, expect := range []string{
+		"//pigo:owner stringer User Names\n",
+		"//pigo:owner stringer Base Names\n",
+		`return strings.Join([]string{"Base,Name,Tags,Owner"}, ",")`,
+		`return strings.Join([]string{"ID"}, ",")`,
+	} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expect %q in:\n%s", expect, src)
+		}
+	}
+	if err := testutil.TypeCheck(g.Outputs.Files()); err != nil {
+		t.Error(err)
+	}
+}
+
+// TestHelperProcess is the command of the ExecPlugin in TestExecPlugin
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("PIGO_TEST_EXEC_PLUGIN") != "1" {
+		return
+	}
+	req := &Request{}
+	resp := &Response{}
+	if err := json.NewDecoder(os.Stdin).Decode(req); err != nil {
+		resp.Error = err.Error()
+	} else if req.Args["fail"] != nil {
+		resp.Error = "failed"
+	} else {
+		fields := []string{}
+		for _, f := range req.Type.Fields {
+			fields = append(fields, fmt.Sprintf("%s %s %v", f.Name, f.Type, f.Embedded))
+		}
+		resp.Code = fmt.Sprintf("// %s is generated by %s\nvar %s = %q\n",
+			req.Args["name"], req.Generator, req.Args["name"], strings.Join(fields, ";"))
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
+
+func TestExecPlugin(t *testing.T) {
+	dir := writeModel(t)
+	defer os.RemoveAll(dir)
+	os.Setenv("PIGO_TEST_EXEC_PLUGIN", "1")
+	defer os.Unsetenv("PIGO_TEST_EXEC_PLUGIN")
+
+	p := NewExecPlugin("fields", os.Args[0], "-test.run=TestHelperProcess")
+	output := filepath.Join(dir, "zz_generated.go")
+	config := p.NewConfig()
+	if err := SetConfig(config, map[string]interface{}{"type": "User", "name": "userFields"}); err != nil {
+		t.Fatal(err)
+	}
+	config.(*ExecConfig).Dir = dir
+	config.(*ExecConfig).Output = output
+
+	g := &generator.Generator{Outputs: &generator.Outputs{}}
+	if err := Run(p, g, config); err != nil {
+		t.Fatal(err)
+	}
+	src := string(g.Outputs.Get(output))
+	for _, expect := range []string{
+		"// userFields is generated by fields\n",
+		"//pigo:owner fields User\n",
+		"var userFields = \"Base Base true;Name string false;Tags []string false;Owner *Base false\"\n",
+	} {
+		if !strings.Contains(src, expect) {
+			t.Errorf("expect %q in:\n%s", expect, src)
+		}
+	}
+
+	config.(*ExecConfig).Args["fail"] = true
+	if err := Run(p, &generator.Generator{Outputs: &generator.Outputs{}}, config); err == nil ||
+		!strings.Contains(err.Error(), "failed") {
+		t.Errorf("expect the error of the response, got %v", err)
+	}
+}